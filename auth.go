@@ -0,0 +1,50 @@
+package osinfo
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isAuthenticated reports whether the request carries the configured basic
+// auth credentials. It is always true when no credentials are configured.
+func isAuthenticated(c *gin.Context) bool {
+	if cfg().authUser == "" {
+		return true
+	}
+	user, pass, ok := c.Request.BasicAuth()
+	if !ok {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(cfg().authUser)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(cfg().authPass)) == 1
+	return userOK && passOK
+}
+
+// hasCredentials reports whether WithBasicAuth is set, logging that the
+// named endpoints are skipped when it isn't. Endpoints that must never be
+// public register only when it returns true, since requireAuth lets every
+// request through without credentials.
+func hasCredentials(endpoints string) bool {
+	if cfg().authUser != "" {
+		return true
+	}
+	log.Printf("osinfo: %s not registered: they need WithBasicAuth", endpoints)
+	return false
+}
+
+// requireAuth guards the sensitive endpoints with basic auth when
+// WithBasicAuth is set
+func requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAuthenticated(c) {
+			c.Header("WWW-Authenticate", `Basic realm="osinfo"`)
+			respond(c, http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,104 @@
+package osinfo
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchCollectors are the metrics that can be requested from /batch
+var batchCollectors = map[string]func() (any, error){
+	"info":    infoCache.get,
+	"uptime":  guarded("uptime", collectUptime),
+	"mem":     guarded("mem", collectMem),
+	"cpu":     guarded("cpu", collectCPU),
+	"disk":    diskCache.get,
+	"network": guarded("network", collectNetwork),
+	"load":    guarded("load", collectLoad),
+	"kernel":  guarded("kernel", collectKernel),
+	"users":   guarded("users", collectUsers),
+}
+
+// guarded wraps collect so it goes through runCollector
+func guarded(name string, collect func() (any, error)) func() (any, error) {
+	return func() (any, error) {
+		return runCollector(name, collect)
+	}
+}
+
+// batchHandler runs the collectors named in ?include=cpu,mem,... concurrently
+// and returns their results keyed by name. A failing collector only affects
+// its own entry.
+func batchHandler(c *gin.Context) {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(c.Query("include"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := batchCollectors[name]; !ok {
+			respond(c, http.StatusBadRequest, gin.H{
+				"error":     "unknown metric: " + name,
+				"available": batchNames(),
+			})
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		respond(c, http.StatusBadRequest, gin.H{
+			"error":     "include must list at least one metric",
+			"available": batchNames(),
+		})
+		return
+	}
+
+	out := runAggregate(names, batchCollectors)
+	respond(c, http.StatusOK, out)
+}
+
+func batchNames() []string {
+	names := make([]string, 0, len(batchCollectors))
+	for name := range batchCollectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runAggregate runs the named collectors concurrently, at most
+// cfg().aggregateConcurrency at a time, and returns their results keyed by
+// name, each trimmed to the WithFields selection of its endpoint. Failures
+// are reported as {"error": ...} for that name only.
+func runAggregate(names []string, collectors map[string]func() (any, error)) gin.H {
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		out = make(gin.H, len(names))
+		sem = make(chan struct{}, cfg().aggregateConcurrency)
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			data, err := collectors[name]()
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				out[name] = gin.H{"error": err.Error()}
+				return
+			}
+			out[name] = projectSection(name, data)
+		}(name)
+	}
+	wg.Wait()
+	return out
+}
@@ -0,0 +1,114 @@
+package osinfo
+
+import (
+	"maps"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ttlCache memoises the result of fetch for the TTL configured for name, see
+// cacheTTL. Errors are not cached.
+type ttlCache struct {
+	name       string
+	defaultTTL time.Duration
+	fetch      func() (any, error)
+
+	mu   sync.Mutex
+	data any
+	at   time.Time
+}
+
+// get returns the value for aggregate responses such as /batch and
+// /dashboard-data; like writeCached, it adds cached_at and age_ms to objects
+// served from the cache
+func (c *ttlCache) get() (any, error) {
+	data, at, hit, err := c.lookup()
+	if err != nil || !hit {
+		return data, err
+	}
+	return withAge(data, at), nil
+}
+
+// lookup returns the value, when it was collected and whether it came from
+// the cache rather than a fresh fetch
+func (c *ttlCache) lookup() (data any, at time.Time, hit bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ttl := cacheTTL(c.name, c.defaultTTL); ttl > 0 && !c.at.IsZero() && since(c.at) < ttl {
+		return c.data, c.at, true, nil
+	}
+	data, err = c.fetch()
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	c.data, c.at = data, clk.Now()
+	return data, c.at, false, nil
+}
+
+// cacheTTL resolves a collector's TTL: its WithCollectorCacheTTL override,
+// else the WithCacheTTL default, else the collector's built-in default
+func cacheTTL(name string, builtin time.Duration) time.Duration {
+	if ttl, ok := cfg().cacheTTLs[name]; ok {
+		return ttl
+	}
+	if cfg().cacheTTLSet {
+		return cfg().cacheTTL
+	}
+	return builtin
+}
+
+// writeCached writes a cached collector result. Responses served from the
+// cache carry an Age header and, for objects, cached_at and age_ms fields.
+func writeCached(c *gin.Context, cache *ttlCache) {
+	data, at, hit, err := cache.lookup()
+	if err != nil {
+		collectorError(c, err)
+		return
+	}
+	if hit {
+		c.Header("Age", strconv.Itoa(int(since(at).Seconds())))
+		data = withAge(data, at)
+	}
+	respond(c, http.StatusOK, data)
+}
+
+// withAge adds cached_at and age_ms to an object collected at at. Other
+// values are returned as is.
+func withAge(data any, at time.Time) any {
+	h, ok := data.(gin.H)
+	if !ok {
+		return data
+	}
+	// copy so the cached value is never modified
+	h = maps.Clone(h)
+	h["cached_at"] = at
+	h["age_ms"] = since(at).Milliseconds()
+	return h
+}
+
+var diskCache = &ttlCache{
+	name:       "disk",
+	defaultTTL: 5 * time.Second,
+	fetch: func() (any, error) {
+		return runCollector("disk", collectDisk)
+	},
+}
+
+var infoCache = &ttlCache{
+	name: "info",
+	fetch: func() (any, error) {
+		return runCollector("info", collectInfo)
+	},
+}
+
+var fullInfoCache = &ttlCache{
+	name: "info",
+	fetch: func() (any, error) {
+		return runCollector("info", collectFullInfo)
+	},
+}
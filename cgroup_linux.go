@@ -0,0 +1,68 @@
+//go:build linux
+
+package osinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupCPUQuota returns the CPU limit of the process's cgroup in cores,
+// e.g. 1.5, and false when there is no limit or it can't be read. Both
+// cgroup v2 (cpu.max) and v1 (cpu.cfs_quota_us) are supported.
+func cgroupCPUQuota() (float64, bool) {
+	for _, dir := range cgroupV2Dirs() {
+		b, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return quotaCores(fields[0], fields[1])
+	}
+	for _, dir := range []string{"/sys/fs/cgroup/cpu,cpuacct", "/sys/fs/cgroup/cpu"} {
+		quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+		if err != nil {
+			continue
+		}
+		period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+		if err != nil {
+			continue
+		}
+		return quotaCores(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+	return 0, false
+}
+
+// cgroupV2Dirs lists the unified-hierarchy directories to look in: the
+// process's own cgroup from /proc/self/cgroup, then the root, which is what
+// a container usually sees
+func cgroupV2Dirs() []string {
+	dirs := []string{}
+	if b, err := os.ReadFile("/proc/self/cgroup"); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if rel, ok := strings.CutPrefix(line, "0::"); ok && rel != "/" {
+				dirs = append(dirs, filepath.Join("/sys/fs/cgroup", rel))
+			}
+		}
+	}
+	return append(dirs, "/sys/fs/cgroup")
+}
+
+// quotaCores divides a CFS quota by its period; a negative quota means
+// unlimited
+func quotaCores(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
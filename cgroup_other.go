@@ -0,0 +1,7 @@
+//go:build !linux
+
+package osinfo
+
+func cgroupCPUQuota() (float64, bool) {
+	return 0, false
+}
@@ -0,0 +1,22 @@
+package osinfo
+
+import "time"
+
+// clock is the time source for request timing, uptimes, caches, circuit
+// breakers and timestamps, so it can be replaced, e.g. in tests. Sampling
+// that has to wait, such as CPU intervals, still sleeps in real time, for
+// the time left on clk.
+type clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+var clk clock = realClock{}
+
+// since is time.Since on clk
+func since(t time.Time) time.Duration {
+	return clk.Now().Sub(t)
+}
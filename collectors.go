@@ -0,0 +1,140 @@
+package osinfo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collectorStatus records the outcome of the most recent calls to a collector
+type collectorStatus struct {
+	LastSuccess         *time.Time `json:"last_success"`
+	LastError           *time.Time `json:"last_error"`
+	Error               string     `json:"error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+	Breaker             string     `json:"breaker"`
+}
+
+var collectorState = struct {
+	mu       sync.RWMutex
+	statuses map[string]*collectorStatus
+}{statuses: make(map[string]*collectorStatus)}
+
+// breakerOpenError is returned instead of calling a collector whose circuit
+// breaker is open
+type breakerOpenError struct {
+	name  string
+	until time.Time
+	last  string
+}
+
+func (e *breakerOpenError) Error() string {
+	return fmt.Sprintf("collector %s unavailable until %s: %s",
+		e.name, e.until.Format(time.RFC3339), e.last)
+}
+
+// runCollector calls collect unless the named collector's breaker is open,
+// and records the outcome
+func runCollector(name string, collect func() (any, error)) (any, error) {
+	if err := breakerCheck(name); err != nil {
+		return nil, err
+	}
+	data, err := retry(collect)
+	recordCollector(name, err)
+	return data, err
+}
+
+// retry calls collect up to cfg().retryAttempts times, doubling the backoff
+// between attempts. Permanent errors are returned immediately.
+func retry(collect func() (any, error)) (any, error) {
+	backoff := cfg().retryBackoff
+	for attempt := 1; ; attempt++ {
+		data, err := collect()
+		if err == nil || attempt >= cfg().retryAttempts || isPermanentError(err) {
+			return data, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isPermanentError reports errors that retrying won't fix
+func isPermanentError(err error) bool {
+	return isPermissionError(err) ||
+		errors.Is(err, errors.ErrUnsupported) ||
+		strings.Contains(err.Error(), "not implemented")
+}
+
+func breakerCheck(name string) error {
+	collectorState.mu.RLock()
+	defer collectorState.mu.RUnlock()
+
+	st, ok := collectorState.statuses[name]
+	if !ok || st.OpenUntil == nil || !clk.Now().Before(*st.OpenUntil) {
+		return nil
+	}
+	return &breakerOpenError{name: name, until: *st.OpenUntil, last: st.Error}
+}
+
+// recordCollector notes a success or failure for the named collector and
+// opens its breaker after too many consecutive failures. Permission errors
+// are recorded but don't count: they are answered as degraded, not outages.
+func recordCollector(name string, err error) {
+	now := clk.Now()
+
+	collectorState.mu.Lock()
+	defer collectorState.mu.Unlock()
+
+	st, ok := collectorState.statuses[name]
+	if !ok {
+		st = &collectorStatus{}
+		collectorState.statuses[name] = st
+	}
+	if err != nil {
+		st.LastError = &now
+		st.Error = err.Error()
+		if isPermissionError(err) {
+			return
+		}
+		st.ConsecutiveFailures++
+		if cfg().breakerThreshold > 0 && st.ConsecutiveFailures >= cfg().breakerThreshold {
+			until := now.Add(cfg().breakerCooldown)
+			st.OpenUntil = &until
+		}
+		return
+	}
+	st.LastSuccess = &now
+	st.ConsecutiveFailures = 0
+	st.OpenUntil = nil
+}
+
+func breakerState(st *collectorStatus, now time.Time) string {
+	switch {
+	case st.OpenUntil == nil:
+		return "closed"
+	case now.Before(*st.OpenUntil):
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+func collectorsHandler(c *gin.Context) {
+	collectorState.mu.RLock()
+	defer collectorState.mu.RUnlock()
+
+	now := clk.Now()
+	out := make(map[string]collectorStatus, len(collectorState.statuses))
+	for name, st := range collectorState.statuses {
+		s := *st
+		s.Breaker = breakerState(st, now)
+		out[name] = s
+	}
+	respond(c, http.StatusOK, out)
+}
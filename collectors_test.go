@@ -0,0 +1,109 @@
+package osinfo
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// flakyCollector returns err from its first few memory reads, as many as
+// failures, and succeeds after that
+type flakyCollector struct {
+	gopsutilCollector
+	failures int
+	err      error
+	calls    *int
+}
+
+func (f flakyCollector) VirtualMemory() (*mem.VirtualMemoryStat, error) {
+	*f.calls++
+	if *f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &mem.VirtualMemoryStat{Total: 100, Used: 40, UsedPercent: 40}, nil
+}
+
+type fakeClock struct{ now time.Time }
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func TestRetryRecoversFromTransientError(t *testing.T) {
+	r := newTestEngine(t, "/os", WithRetry(3, 0))
+	var calls int
+	sys = flakyCollector{failures: 1, err: errors.New("read /proc/meminfo: resource temporarily unavailable"), calls: &calls}
+
+	if w := get(r, "/os/mem"); w.Code != http.StatusOK {
+		t.Fatalf("GET /os/mem = %d, want 200 after a retry", w.Code)
+	}
+	if calls != 2 {
+		t.Errorf("collector called %d times, want 2", calls)
+	}
+}
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	r := newTestEngine(t, "/os", WithRetry(3, 0))
+	var calls int
+	sys = flakyCollector{failures: 1, err: errors.ErrUnsupported, calls: &calls}
+
+	if w := get(r, "/os/mem"); w.Code != http.StatusInternalServerError {
+		t.Errorf("GET /os/mem = %d, want 500", w.Code)
+	}
+	if calls != 1 {
+		t.Errorf("collector called %d times, want 1: permanent errors are not retried", calls)
+	}
+}
+
+func TestBreakerClosesAfterSuccess(t *testing.T) {
+	r := newTestEngine(t, "/os", WithRetry(1, 0), WithCircuitBreaker(2, time.Minute))
+	fake := &fakeClock{now: time.Now()}
+	clk = fake
+	var calls int
+	sys = flakyCollector{failures: 2, err: errors.New("transient"), calls: &calls}
+
+	for i := 0; i < 2; i++ {
+		if w := get(r, "/os/mem"); w.Code != http.StatusInternalServerError {
+			t.Fatalf("failure %d: GET /os/mem = %d, want 500", i+1, w.Code)
+		}
+	}
+	w := get(r, "/os/mem")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("open breaker: GET /os/mem = %d, want 503 with Retry-After", w.Code)
+	}
+	if calls != 2 {
+		t.Fatalf("collector called %d times, want 2: an open breaker skips it", calls)
+	}
+
+	fake.now = fake.now.Add(time.Minute)
+	if w := get(r, "/os/mem"); w.Code != http.StatusOK {
+		t.Fatalf("after cooldown: GET /os/mem = %d, want 200", w.Code)
+	}
+	collectorState.mu.RLock()
+	st := *collectorState.statuses["mem"]
+	collectorState.mu.RUnlock()
+	if st.ConsecutiveFailures != 0 || breakerState(&st, fake.now) != "closed" {
+		t.Errorf("after success: %d failures, breaker %s; want 0 and closed",
+			st.ConsecutiveFailures, breakerState(&st, fake.now))
+	}
+}
+
+func TestConfigBreakerThresholdZeroDisables(t *testing.T) {
+	off := 0
+	r := newTestEngine(t, "/os", WithConfig(Config{BreakerThreshold: &off, RetryAttempts: 1}))
+	var calls int
+	sys = flakyCollector{failures: 10, err: errors.New("transient"), calls: &calls}
+
+	for i := 0; i < 10; i++ {
+		if w := get(r, "/os/mem"); w.Code != http.StatusInternalServerError {
+			t.Fatalf("failure %d: GET /os/mem = %d, want 500 with the breaker disabled", i+1, w.Code)
+		}
+	}
+	if calls != 10 {
+		t.Errorf("collector called %d times, want 10: a disabled breaker never skips it", calls)
+	}
+	if w := get(r, "/os/mem"); w.Code != http.StatusOK {
+		t.Errorf("after recovery: GET /os/mem = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,207 @@
+package osinfo
+
+import (
+	"log"
+	"time"
+)
+
+// Config holds every osinfo setting in one struct, for applications that
+// drive their configuration from a file. Zero values keep the defaults. Pass
+// it to RegisterRoutes with WithConfig; options given after WithConfig
+// override it.
+type Config struct {
+	// Prefix, when set, replaces the prefix passed to the RegisterRoutes
+	// call that receives this Config; later calls keep their own prefix
+	Prefix string `json:"prefix" yaml:"prefix"`
+	// DisabledEndpoints lists endpoint paths, relative to the prefix, that
+	// are not registered, e.g. "/env"
+	DisabledEndpoints []string      `json:"disabled_endpoints" yaml:"disabled_endpoints"`
+	DashboardPath     string        `json:"dashboard_path" yaml:"dashboard_path"`
+	DashboardLayout   []string      `json:"dashboard_layout" yaml:"dashboard_layout"`
+	DashboardRefresh  time.Duration `json:"dashboard_refresh" yaml:"dashboard_refresh"`
+	MaxStreamClients  int           `json:"max_stream_clients" yaml:"max_stream_clients"`
+
+	// Fields maps endpoint paths to the fields they return, see WithFields
+	Fields map[string][]string `json:"fields" yaml:"fields"`
+
+	BasicAuthUser     string `json:"basic_auth_user" yaml:"basic_auth_user"`
+	BasicAuthPassword string `json:"basic_auth_password" yaml:"basic_auth_password"`
+	TieredEnv         bool   `json:"tiered_env" yaml:"tiered_env"`
+	Profiling         bool   `json:"profiling" yaml:"profiling"`
+	CacheControl      string `json:"cache_control" yaml:"cache_control"`
+
+	ExcludeFstypes    []string      `json:"exclude_fstypes" yaml:"exclude_fstypes"`
+	PhysicalDisksOnly bool          `json:"physical_disks_only" yaml:"physical_disks_only"`
+	ExcludeNetworkFS  bool          `json:"exclude_network_fs" yaml:"exclude_network_fs"`
+	DiskUsageTimeout  time.Duration `json:"disk_usage_timeout" yaml:"disk_usage_timeout"`
+	MaxPartitions     int           `json:"max_partitions" yaml:"max_partitions"`
+
+	SecurityHeaders map[string]string `json:"security_headers" yaml:"security_headers"`
+	// EmbedAncestors enables WithEmbeddable with these origins
+	EmbedAncestors []string `json:"embed_ancestors" yaml:"embed_ancestors"`
+	Envelope       bool     `json:"envelope" yaml:"envelope"`
+	// PercentPrecision is a pointer because 0 decimal places is valid
+	PercentPrecision *int `json:"percent_precision" yaml:"percent_precision"`
+
+	LatencyBuckets   []float64         `json:"latency_buckets" yaml:"latency_buckets"`
+	MetricNamespace  string            `json:"metric_namespace" yaml:"metric_namespace"`
+	OpenMetrics      bool              `json:"open_metrics" yaml:"open_metrics"`
+	ConstantLabels   map[string]string `json:"constant_labels" yaml:"constant_labels"`
+	Expvar           bool              `json:"expvar" yaml:"expvar"`
+	SystemMetrics    bool              `json:"system_metrics" yaml:"system_metrics"`
+	MaxTrackedRoutes int               `json:"max_tracked_routes" yaml:"max_tracked_routes"`
+	DimensionLabels  bool              `json:"dimension_labels" yaml:"dimension_labels"`
+	GinErrors        bool              `json:"gin_errors" yaml:"gin_errors"`
+	SampleRate       float64           `json:"sample_rate" yaml:"sample_rate"`
+	SlowThreshold    time.Duration     `json:"slow_threshold" yaml:"slow_threshold"`
+	LatencyHalfLife  time.Duration     `json:"latency_half_life" yaml:"latency_half_life"`
+
+	// DailyReset enables WithDailyReset in DailyResetTimezone, an IANA name
+	// such as "Europe/Berlin"; "" means UTC and "Local" the host's zone
+	DailyReset         bool   `json:"daily_reset" yaml:"daily_reset"`
+	DailyResetTimezone string `json:"daily_reset_timezone" yaml:"daily_reset_timezone"`
+
+	ProcessLimit         int               `json:"process_limit" yaml:"process_limit"`
+	RootMount            string            `json:"root_mount" yaml:"root_mount"`
+	DiskCacheTTL         time.Duration     `json:"disk_cache_ttl" yaml:"disk_cache_ttl"`
+	CPUSamples           int               `json:"cpu_samples" yaml:"cpu_samples"`
+	CPUSampleBudget      time.Duration     `json:"cpu_sample_budget" yaml:"cpu_sample_budget"`
+	AggregateConcurrency int               `json:"aggregate_concurrency" yaml:"aggregate_concurrency"`
+	NetNamespace         string            `json:"net_namespace" yaml:"net_namespace"`
+	IdentityEnv          map[string]string `json:"identity_env" yaml:"identity_env"`
+
+	// CacheTTL is the default for the cached collectors; CollectorCacheTTLs
+	// overrides it per collector, e.g. "info"
+	CacheTTL           time.Duration            `json:"cache_ttl" yaml:"cache_ttl"`
+	CollectorCacheTTLs map[string]time.Duration `json:"collector_cache_ttls" yaml:"collector_cache_ttls"`
+
+	// BreakerThreshold is a pointer because 0 disables the breaker
+	BreakerThreshold *int          `json:"breaker_threshold" yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `json:"breaker_cooldown" yaml:"breaker_cooldown"`
+	RetryAttempts    int           `json:"retry_attempts" yaml:"retry_attempts"`
+	RetryBackoff     time.Duration `json:"retry_backoff" yaml:"retry_backoff"`
+
+	Thresholds         Thresholds    `json:"thresholds" yaml:"thresholds"`
+	CheckTimeout       time.Duration `json:"check_timeout" yaml:"check_timeout"`
+	HealthCheckTimeout time.Duration `json:"health_check_timeout" yaml:"health_check_timeout"`
+	ReadinessDelay     time.Duration `json:"readiness_delay" yaml:"readiness_delay"`
+	WaitForReady       bool          `json:"wait_for_ready" yaml:"wait_for_ready"`
+	EmptyHealthBody    bool          `json:"empty_health_body" yaml:"empty_health_body"`
+	HealthDetails      bool          `json:"health_details" yaml:"health_details"`
+	DegradedUnready    bool          `json:"degraded_unready" yaml:"degraded_unready"`
+	AlertWebhook       string        `json:"alert_webhook" yaml:"alert_webhook"`
+	AlertInterval      time.Duration `json:"alert_interval" yaml:"alert_interval"`
+	AlertDebounce      int           `json:"alert_debounce" yaml:"alert_debounce"`
+
+	StatsDAddr   string `json:"statsd_addr" yaml:"statsd_addr"`
+	StatsDPrefix string `json:"statsd_prefix" yaml:"statsd_prefix"`
+
+	// GoroutineHistory enables WithGoroutineHistory with
+	// GoroutineHistoryInterval and GoroutineHistorySamples
+	GoroutineHistory         bool          `json:"goroutine_history" yaml:"goroutine_history"`
+	GoroutineHistoryInterval time.Duration `json:"goroutine_history_interval" yaml:"goroutine_history_interval"`
+	GoroutineHistorySamples  int           `json:"goroutine_history_samples" yaml:"goroutine_history_samples"`
+
+	// RequestLog enables WithRequestLog with RequestLogSize entries
+	RequestLog     bool `json:"request_log" yaml:"request_log"`
+	RequestLogSize int  `json:"request_log_size" yaml:"request_log_size"`
+}
+
+// WithConfig applies every non-zero field of cfg through the matching
+// option.
+func WithConfig(cfg Config) Option {
+	return func(c *config) {
+		var opts []Option
+		add := func(ok bool, opt Option) {
+			if ok {
+				opts = append(opts, opt)
+			}
+		}
+
+		add(cfg.Prefix != "", func(c *config) { c.prefix = cfg.Prefix })
+		add(len(cfg.DisabledEndpoints) > 0, WithoutEndpoints(cfg.DisabledEndpoints...))
+		for endpoint, fields := range cfg.Fields {
+			opts = append(opts, WithFields(endpoint, fields...))
+		}
+		add(cfg.DashboardPath != "", WithDashboardPath(cfg.DashboardPath))
+		add(len(cfg.DashboardLayout) > 0, WithDashboardLayout(cfg.DashboardLayout))
+		add(cfg.DashboardRefresh > 0, WithDashboardRefresh(cfg.DashboardRefresh))
+		add(cfg.MaxStreamClients > 0, WithMaxStreamClients(cfg.MaxStreamClients))
+		add(cfg.BasicAuthUser != "", WithBasicAuth(cfg.BasicAuthUser, cfg.BasicAuthPassword))
+		add(cfg.TieredEnv, WithTieredEnv())
+		add(cfg.Profiling, WithProfiling())
+		add(cfg.CacheControl != "", WithCacheControl(cfg.CacheControl))
+		add(len(cfg.ExcludeFstypes) > 0, WithExcludeFstypes(cfg.ExcludeFstypes...))
+		add(cfg.PhysicalDisksOnly, WithPhysicalDisksOnly())
+		add(cfg.ExcludeNetworkFS, WithNetworkFilesystems(false))
+		add(cfg.DiskUsageTimeout > 0, WithDiskUsageTimeout(cfg.DiskUsageTimeout))
+		add(cfg.MaxPartitions > 0, WithMaxPartitions(cfg.MaxPartitions))
+		add(len(cfg.SecurityHeaders) > 0, WithSecurityHeaders(cfg.SecurityHeaders))
+		add(len(cfg.EmbedAncestors) > 0, WithEmbeddable(cfg.EmbedAncestors...))
+		add(cfg.Envelope, WithEnvelope())
+		add(cfg.PercentPrecision != nil, func(c *config) {
+			WithPercentPrecision(*cfg.PercentPrecision)(c)
+		})
+
+		add(len(cfg.LatencyBuckets) > 0, WithLatencyBuckets(cfg.LatencyBuckets))
+		add(cfg.MetricNamespace != "", WithMetricNamespace(cfg.MetricNamespace))
+		add(cfg.OpenMetrics, WithOpenMetrics())
+		add(len(cfg.ConstantLabels) > 0, WithConstantLabels(cfg.ConstantLabels))
+		add(cfg.Expvar, WithExpvar())
+		add(cfg.SystemMetrics, WithSystemMetrics())
+		add(cfg.MaxTrackedRoutes > 0, WithMaxTrackedRoutes(cfg.MaxTrackedRoutes))
+		add(cfg.DimensionLabels, WithDimensionLabels())
+		add(cfg.GinErrors, WithGinErrors())
+		add(cfg.SampleRate > 0, WithSampleRate(cfg.SampleRate))
+		add(cfg.SlowThreshold > 0, WithSlowThreshold(cfg.SlowThreshold))
+		add(cfg.LatencyHalfLife > 0, WithLatencyHalfLife(cfg.LatencyHalfLife))
+		add(cfg.DailyReset, func(c *config) {
+			loc, err := time.LoadLocation(cfg.DailyResetTimezone)
+			if err != nil {
+				log.Printf("osinfo: invalid daily reset timezone %q, using local time: %v", cfg.DailyResetTimezone, err)
+				loc = time.Local
+			}
+			WithDailyReset(loc)(c)
+		})
+
+		add(cfg.ProcessLimit > 0, WithProcessLimit(cfg.ProcessLimit))
+		add(cfg.RootMount != "", WithRootMount(cfg.RootMount))
+		add(cfg.DiskCacheTTL > 0, WithDiskCacheTTL(cfg.DiskCacheTTL))
+		add(cfg.CacheTTL > 0, WithCacheTTL(cfg.CacheTTL))
+		for name, ttl := range cfg.CollectorCacheTTLs {
+			opts = append(opts, WithCollectorCacheTTL(name, ttl))
+		}
+		add(cfg.CPUSamples > 0 || cfg.CPUSampleBudget > 0, WithCPUSampling(cfg.CPUSamples, cfg.CPUSampleBudget))
+		add(cfg.AggregateConcurrency > 0, WithAggregateConcurrency(cfg.AggregateConcurrency))
+		add(cfg.NetNamespace != "", WithNetNamespace(cfg.NetNamespace))
+		add(len(cfg.IdentityEnv) > 0, WithIdentityEnv(cfg.IdentityEnv))
+
+		add(cfg.BreakerThreshold != nil, func(c *config) { c.breakerThreshold = *cfg.BreakerThreshold })
+		add(cfg.BreakerCooldown > 0, func(c *config) { c.breakerCooldown = cfg.BreakerCooldown })
+		add(cfg.RetryAttempts > 0 || cfg.RetryBackoff > 0, func(c *config) {
+			if cfg.RetryAttempts > 0 {
+				c.retryAttempts = cfg.RetryAttempts
+			}
+			if cfg.RetryBackoff > 0 {
+				c.retryBackoff = cfg.RetryBackoff
+			}
+		})
+
+		add(cfg.Thresholds != Thresholds{}, WithThresholds(cfg.Thresholds))
+		add(cfg.CheckTimeout > 0 || cfg.HealthCheckTimeout > 0, WithHealthCheckTimeout(cfg.CheckTimeout, cfg.HealthCheckTimeout))
+		add(cfg.ReadinessDelay > 0, WithReadinessDelay(cfg.ReadinessDelay))
+		add(cfg.WaitForReady, WithWaitForReady())
+		add(cfg.EmptyHealthBody, WithEmptyHealthBody())
+		add(cfg.HealthDetails, WithHealthDetails())
+		add(cfg.DegradedUnready, WithDegradedUnready())
+		add(cfg.AlertWebhook != "", WithAlertWebhook(cfg.AlertWebhook, cfg.AlertInterval))
+		add(cfg.AlertDebounce > 0, WithAlertDebounce(cfg.AlertDebounce))
+		add(cfg.StatsDAddr != "", WithStatsD(cfg.StatsDAddr, cfg.StatsDPrefix))
+		add(cfg.GoroutineHistory, WithGoroutineHistory(cfg.GoroutineHistoryInterval, cfg.GoroutineHistorySamples))
+		add(cfg.RequestLog, WithRequestLog(cfg.RequestLogSize))
+
+		for _, opt := range opts {
+			opt(c)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package osinfo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	cpu "github.com/shirou/gopsutil/v3/cpu"
+)
+
+// cpuSampleInterval is the window of a single CPU utilisation sample
+const cpuSampleInterval = 500 * time.Millisecond
+
+// cpuHandler reports CPU utilisation. ?samples=N averages N consecutive
+// samples, capped so the request stays within the configured time budget.
+// ?windows=0.5s,5s reports utilisation over several windows at once.
+func cpuHandler(c *gin.Context) {
+	if q := c.Query("windows"); q != "" {
+		cpuWindowsHandler(c, q)
+		return
+	}
+
+	samples := cfg().cpuSamples
+	if n, err := strconv.Atoi(c.Query("samples")); err == nil && n > 0 {
+		samples = n
+	}
+	writeCollected(c, "cpu", func() (any, error) {
+		return collectCPUSamples(samples)
+	})
+}
+
+// cpuBaseline is one consumer's previous reading of the CPU times. Each
+// consumer diffs against its own baseline, so a reading covers the time since
+// that consumer last asked rather than since any caller did.
+type cpuBaseline struct {
+	mu      sync.Mutex
+	prev    cpu.TimesStat
+	at      time.Time
+	percent float64
+	read    bool
+}
+
+// The consumers of instant CPU readings, each with its own baseline
+var (
+	healthCPU     cpuBaseline
+	readinessCPU  cpuBaseline
+	alertCPU      cpuBaseline
+	gaugesCPU     cpuBaseline
+	exportCPU     cpuBaseline
+	statsdCPU     cpuBaseline
+	snapshotCPU   cpuBaseline
+	prometheusCPU cpuBaseline
+
+	cpuBaselines = []*cpuBaseline{
+		&healthCPU, &readinessCPU, &alertCPU, &gaugesCPU,
+		&exportCPU, &statsdCPU, &snapshotCPU, &prometheusCPU,
+	}
+)
+
+// primeCPUSampler takes the baselines instant readings diff against, so the
+// first reading of each consumer covers the time since registration.
+func primeCPUSampler() {
+	for _, b := range cpuBaselines {
+		b.mu.Lock()
+		if b.at.IsZero() {
+			b.sample()
+		}
+		b.mu.Unlock()
+	}
+}
+
+// sample replaces the baseline with the current CPU times and returns the
+// utilisation since the previous one. The caller holds b.mu.
+func (b *cpuBaseline) sample() (float64, error) {
+	times, err := sys.CPUTimes(false)
+	if err != nil {
+		return 0, err
+	}
+	if len(times) == 0 {
+		return 0, errors.New("no CPU times available")
+	}
+	p := busyPercent(b.prev, times[0])
+	b.prev, b.at = times[0], clk.Now()
+	return p, nil
+}
+
+// instantCPUPercent returns utilisation since b's previous reading, as used
+// by the gauges and threshold checks. Readings less than a sample interval
+// apart return the previous value, and the first reading waits until a full
+// interval has passed since priming, so each value covers a meaningful
+// window. /cpu is unaffected, it always samples over its own interval.
+func instantCPUPercent(b *cpuBaseline) ([]float64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.at.IsZero() {
+		if _, err := b.sample(); err != nil {
+			return nil, err
+		}
+	}
+	if wait := b.at.Add(cpuSampleInterval).Sub(clk.Now()); wait > 0 {
+		if b.read {
+			return []float64{b.percent}, nil
+		}
+		time.Sleep(wait)
+	}
+	p, err := b.sample()
+	if err != nil {
+		return nil, err
+	}
+	b.percent, b.read = p, true
+	return []float64{p}, nil
+}
+
+func collectCPU() (any, error) {
+	return collectCPUSamples(cfg().cpuSamples)
+}
+
+func collectCPUSamples(samples int) (any, error) {
+	if limit := int(cfg().cpuSampleBudget / cpuSampleInterval); samples > limit {
+		samples = limit
+	}
+	if samples < 1 {
+		samples = 1
+	}
+
+	var sum, lo, hi float64
+	for i := 0; i < samples; i++ {
+		percent, err := sys.CPUPercent(cpuSampleInterval, false)
+		if err != nil {
+			return nil, err
+		}
+		if samples == 1 {
+			return gin.H{"cpu_percent": percent}, nil
+		}
+		p := percent[0]
+		if i == 0 || p < lo {
+			lo = p
+		}
+		if i == 0 || p > hi {
+			hi = p
+		}
+		sum += p
+	}
+	avg := sum / float64(samples)
+	return gin.H{
+		"cpu_percent":     []float64{avg},
+		"samples":         samples,
+		"min_percent":     lo,
+		"max_percent":     hi,
+		"avg_percent":     avg,
+		"sample_interval": cpuSampleInterval.String(),
+	}, nil
+}
+
+// maxCPUWindows caps how many windows one request may ask for
+const maxCPUWindows = 5
+
+// cpuWindowsHandler measures utilisation over each requested window, all
+// starting from the same snapshot of CPU times, so the request takes as
+// long as the longest window.
+func cpuWindowsHandler(c *gin.Context, q string) {
+	var windows []time.Duration
+	for _, w := range strings.Split(q, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(w))
+		if err != nil || d <= 0 {
+			respond(c, http.StatusBadRequest, gin.H{"error": "invalid window: " + w})
+			return
+		}
+		if d > cfg().cpuSampleBudget {
+			respond(c, http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("window %s exceeds the %s limit", d, cfg().cpuSampleBudget),
+			})
+			return
+		}
+		windows = append(windows, d)
+	}
+	if len(windows) > maxCPUWindows {
+		respond(c, http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("at most %d windows may be requested", maxCPUWindows),
+		})
+		return
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+
+	data, err := runCollector("cpu", func() (any, error) {
+		return collectCPUWindows(windows)
+	})
+	writeResult(c, data, err)
+}
+
+func collectCPUWindows(windows []time.Duration) (any, error) {
+	start := clk.Now()
+	base, err := sys.CPUTimes(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(base) == 0 {
+		return nil, errors.New("no CPU times available")
+	}
+
+	out := make([]gin.H, 0, len(windows))
+	for _, w := range windows {
+		time.Sleep(start.Add(w).Sub(clk.Now()))
+		now, err := sys.CPUTimes(false)
+		if err != nil {
+			return nil, err
+		}
+		if len(now) == 0 {
+			return nil, errors.New("no CPU times available")
+		}
+		out = append(out, gin.H{
+			"window":      w.String(),
+			"cpu_percent": busyPercent(base[0], now[0]),
+		})
+	}
+	return gin.H{"windows": out}, nil
+}
+
+// busyPercent is the share of non-idle CPU time between two samples
+func busyPercent(t1, t2 cpu.TimesStat) float64 {
+	busy := func(t cpu.TimesStat) (float64, float64) {
+		total := t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+		return total - t.Idle - t.Iowait, total
+	}
+	b1, all1 := busy(t1)
+	b2, all2 := busy(t2)
+	if all2 <= all1 {
+		return 0
+	}
+	if b2 <= b1 {
+		return 0
+	}
+	return math.Min(100, (b2-b1)/(all2-all1)*100)
+}
+
+// cpuAllocHandler compares the CPUs the runtime sees and uses with the
+// cgroup CPU limit. A GOMAXPROCS above the limit makes the process run more
+// threads than it gets CPU time for, and it is throttled.
+func cpuAllocHandler(c *gin.Context) {
+	numCPU := runtime.NumCPU()
+	gomaxprocs := runtime.GOMAXPROCS(0)
+	out := gin.H{
+		"num_cpu":          numCPU,
+		"gomaxprocs":       gomaxprocs,
+		"cgroup_cpu_quota": nil,
+		"mismatch":         false,
+	}
+	if quota, ok := cgroupCPUQuota(); ok {
+		limit := int(math.Ceil(quota))
+		out["cgroup_cpu_quota"] = quota
+		out["effective_cpus"] = min(numCPU, limit)
+		if gomaxprocs > limit {
+			out["mismatch"] = true
+			out["note"] = fmt.Sprintf("GOMAXPROCS %d exceeds the cgroup CPU limit of %g; consider setting it to %d",
+				gomaxprocs, quota, limit)
+		}
+	}
+	respond(c, http.StatusOK, out)
+}
+
+// cpuTimesHandler returns the raw cumulative CPU times, in seconds since
+// boot, for the whole machine or with ?percpu=true for each core. Unlike
+// /cpu these are not percentages; callers derive rates from two readings.
+func cpuTimesHandler(c *gin.Context) {
+	percpu, _ := strconv.ParseBool(c.Query("percpu"))
+	writeCollected(c, "cpu", func() (any, error) {
+		times, err := sys.CPUTimes(percpu)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"percpu": percpu, "times": times}, nil
+	})
+}
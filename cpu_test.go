@@ -0,0 +1,60 @@
+package osinfo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// scriptedTimes reports the cumulative CPU times the test sets, counting
+// how often they are read
+type scriptedTimes struct {
+	gopsutilCollector
+	times *cpu.TimesStat
+	reads *int
+}
+
+func (s scriptedTimes) CPUTimes(bool) ([]cpu.TimesStat, error) {
+	*s.reads++
+	return []cpu.TimesStat{*s.times}, nil
+}
+
+func TestInstantCPUPercentPerConsumer(t *testing.T) {
+	resetGlobals(t)
+	times := &cpu.TimesStat{User: 100, Idle: 100}
+	var reads int
+	sys = scriptedTimes{times: times, reads: &reads}
+	fake := &fakeClock{now: time.Now()}
+	clk = fake
+	primeCPUSampler()
+
+	read := func(b *cpuBaseline, want float64) {
+		t.Helper()
+		p, err := instantCPUPercent(b)
+		if err != nil || len(p) != 1 || p[0] != want {
+			t.Fatalf("instantCPUPercent = %v, %v; want %v", p, err, want)
+		}
+	}
+
+	// a busy second, then an idle one
+	times.User, times.Idle = 150, 150
+	fake.now = fake.now.Add(time.Second)
+	read(&prometheusCPU, 50)
+	times.Idle = 250
+	fake.now = fake.now.Add(time.Second)
+	read(&prometheusCPU, 0)
+
+	// the readiness check has not read since priming, so scrapes in between
+	// do not shorten its window: 50 of the 200 seconds were busy
+	read(&readinessCPU, 25)
+
+	// a reading within the sample interval repeats the previous value
+	n := reads
+	times.User = 500
+	fake.now = fake.now.Add(cpuSampleInterval / 2)
+	read(&prometheusCPU, 0)
+	if reads != n {
+		t.Errorf("CPU times read %d more times within one interval, want none", reads-n)
+	}
+}
@@ -0,0 +1,99 @@
+package osinfo
+
+import (
+	"encoding/csv"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// latencyWindowSize is how many recent latencies are kept per route for the
+// percentiles
+const latencyWindowSize = 256
+
+// latencyWindow is a ring of the most recent latencies of one route
+type latencyWindow struct {
+	samples [latencyWindowSize]time.Duration
+	n       int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.samples[w.n%latencyWindowSize] = d
+	w.n++
+}
+
+func (w *latencyWindow) values() []time.Duration {
+	return append([]time.Duration(nil), w.samples[:min(w.n, latencyWindowSize)]...)
+}
+
+// routeLatencies collects the recent latencies of every route, sorted
+func (m *Metrics) routeLatencies() map[string][]time.Duration {
+	out := make(map[string][]time.Duration)
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		for route, w := range s.latencies {
+			out[route] = append(out[route], w.values()...)
+		}
+		s.mu.Unlock()
+	}
+	for _, l := range out {
+		slices.Sort(l)
+	}
+	return out
+}
+
+// percentileMs returns the p-th percentile of sorted in milliseconds, using
+// the nearest-rank method
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted))+0.999999) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return float64(sorted[rank]) / float64(time.Millisecond)
+}
+
+// metricsCSVHandler downloads the per-route metrics as CSV. The percentiles
+// cover each route's last 256 requests; count, average and bytes cover all
+// of them.
+func metricsCSVHandler(c *gin.Context) {
+	routes := metrics.Routes()
+	latencies := metrics.routeLatencies()
+	names := make([]string, 0, len(routes))
+	for route := range routes {
+		names = append(names, route)
+	}
+	sort.Strings(names)
+
+	setCacheControl(c)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="osinfo-metrics.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"route", "count", "avg_ms", "p50_ms", "p90_ms", "p99_ms", "bytes"})
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) }
+	for _, route := range names {
+		rs := routes[route]
+		avg := 0.0
+		if rs.Count > 0 {
+			avg = float64(rs.TotalResponseTime) / float64(rs.Count)
+		}
+		l := latencies[route]
+		w.Write([]string{
+			route,
+			strconv.FormatInt(rs.Count, 10),
+			format(avg),
+			format(percentileMs(l, 50)),
+			format(percentileMs(l, 90)),
+			format(percentileMs(l, 99)),
+			strconv.FormatInt(rs.Bytes, 10),
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,26 @@
+package osinfo
+
+import "time"
+
+// runDailyReset zeroes metrics.TodayRequests at each midnight in loc until
+// stop is closed
+func runDailyReset(loc *time.Location, stop <-chan struct{}) {
+	for {
+		now := clk.Now().In(loc)
+		timer := time.NewTimer(nextMidnight(now).Sub(now))
+		select {
+		case <-timer.C:
+			metrics.TodayRequests.Store(0)
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// nextMidnight returns the start of the day after t, in t's location. Using
+// time.Date rather than adding 24h keeps it right across DST changes.
+func nextMidnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+}
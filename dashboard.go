@@ -1,9 +1,15 @@
 package osinfo
 
 import (
+	"bytes"
 	"embed"
 	"html/template"
+	"io/fs"
+	"mime"
 	"net/http"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,31 +17,188 @@ import (
 //go:embed templates
 var embeddedFiles embed.FS
 
-var dashboardTemplate *template.Template
+var dashboardTemplate, templateErr = template.ParseFS(embeddedFiles, "templates/*.html")
 
-func init() {
-	tmpl, err := template.ParseFS(embeddedFiles, "templates/*.html")
-	if err != nil {
-		panic(err)
-	}
-	dashboardTemplate = tmpl
+// TemplateError returns the error from parsing the embedded dashboard
+// templates, or nil if the dashboard is functional. A parse failure no longer
+// panics at init; the dashboard responds with 500 instead, and the JSON
+// endpoints are unaffected.
+func TemplateError() error {
+	return templateErr
 }
 
+// The dashboard page and its assets only change with the binary, so unlike
+// the JSON endpoints they may be cached
+const (
+	dashboardCacheControl = "public, max-age=300"
+	staticCacheControl    = "public, max-age=3600"
+)
+
 // Serve dashboard HTML
 func serveDashboard(c *gin.Context) {
-	c.Status(http.StatusOK)
-	c.Header("Content-Type", "text/html; charset=utf-8")
+	base := strings.TrimSuffix(c.FullPath(), cfg().dashboardPath)
+	c.Header("Cache-Control", dashboardCacheControl)
+	renderDashboard(c, gin.H{
+		"title":   "OS Metrics Dashboard",
+		"dataURL": path.Join("/", base, "dashboard-data"),
+	})
+}
 
-	err := dashboardTemplate.ExecuteTemplate(c.Writer, "dashboard.html", gin.H{
-		"title": "OS Metrics Dashboard",
+// exportDashboardHandler renders the dashboard as a standalone HTML
+// download holding the current data, e.g. to attach to an incident ticket.
+// The page shows the captured values instead of polling and loads nothing
+// from the network: it carries its own minimal CSS and leaves out the
+// charts, which a single sample can't fill anyway.
+func exportDashboardHandler(c *gin.Context) {
+	now := clk.Now().UTC()
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition",
+		`attachment; filename="dashboard-`+now.Format("20060102-150405")+`.html"`)
+	renderDashboard(c, gin.H{
+		"title":    "OS Metrics Dashboard (snapshot " + now.Format(time.RFC3339) + ")",
+		"snapshot": cleanDashboardData(),
 	})
-	if err != nil {
+}
+
+// renderDashboard executes the dashboard template with data plus the
+// settings shared by the live and exported pages
+func renderDashboard(c *gin.Context, data gin.H) {
+	if templateErr != nil {
+		c.String(http.StatusInternalServerError, "Template error: %v", templateErr)
+		return
+	}
+	data["embedded"] = cfg().embeddable
+	data["layout"] = dashboardLayout()
+	data["refreshMs"] = cfg().dashboardRefresh.Milliseconds()
+
+	var buf bytes.Buffer
+	if err := dashboardTemplate.ExecuteTemplate(&buf, "dashboard.html", data); err != nil {
 		c.String(http.StatusInternalServerError, "Template error: %v", err)
+		return
 	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
 }
 
-// Serve static files
+// staticHandler serves the embedded assets. Paths that try to leave the asset
+// root get a 400, missing files and directories a plain 404, and the
+// Content-Type follows the file extension.
 func staticHandler(c *gin.Context) {
-	file := c.Param("filepath")
-	c.FileFromFS(file, http.FS(embeddedFiles))
+	name := strings.TrimPrefix(c.Param("filepath"), "/")
+	if !validAssetPath(name) {
+		c.String(http.StatusBadRequest, "invalid asset path")
+		return
+	}
+	data, err := fs.ReadFile(embeddedFiles, name)
+	if err != nil {
+		c.String(http.StatusNotFound, "asset not found: %s", name)
+		return
+	}
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = http.DetectContentType(data)
+	}
+	c.Header("Cache-Control", staticCacheControl)
+	c.Data(http.StatusOK, ctype, data)
+}
+
+// validAssetPath rejects traversal ("..") and absolute paths, including
+// Windows forms such as C:\x or \\host\share, so assets can only come from
+// beneath the asset root whatever filesystem backs it
+func validAssetPath(name string) bool {
+	if strings.ContainsAny(name, `\:`) {
+		return false
+	}
+	return name == "" || fs.ValidPath(name)
+}
+
+type dashboardPanel struct{ name, section string }
+
+// dashboardPanels lists the dashboard panels in their default order, with the
+// /dashboard-data section each one renders
+var dashboardPanels = []dashboardPanel{
+	{"health", "health"},
+	{"cpu", "cpu"},
+	{"mem", "mem"},
+	{"disk", "disk"},
+	{"network", "network"},
+	{"requests", "metrics"},
+	{"latency", "metrics"},
+	{"custom", "metrics"},
+	{"endpoints", "endpoints"},
+	{"cpu_chart", "cpu"},
+	{"mem_chart", "mem"},
+	{"requests_chart", "metrics"},
+}
+
+// dashboardLayout returns the panels to show, in order: the configured
+// layout or the default one, minus panels whose endpoint is disabled
+func dashboardLayout() []string {
+	sections := make(map[string]string, len(dashboardPanels))
+	names := make([]string, 0, len(dashboardPanels))
+	for _, p := range dashboardPanels {
+		sections[p.name] = p.section
+		names = append(names, p.name)
+	}
+	if cfg().dashboardLayout != nil {
+		names = cfg().dashboardLayout
+	}
+
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		section, ok := sections[name]
+		if ok && !cfg().disabled[dashboardSections[section]] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// dashboardSections maps each dashboard section to the endpoint it mirrors;
+// sections whose endpoint is disabled are left out of /dashboard-data
+var dashboardSections = map[string]string{
+	"health":    "/health",
+	"cpu":       "/cpu",
+	"mem":       "/mem",
+	"disk":      "/disk",
+	"network":   "/network",
+	"uptime":    "/uptime",
+	"metrics":   "/metrics",
+	"endpoints": "/routes",
+}
+
+var dashboardCollectors = map[string]func() (any, error){
+	"health":    func() (any, error) { return gin.H{"status": "ok"}, nil },
+	"cpu":       guarded("cpu", collectCPU),
+	"mem":       guarded("mem", collectMem),
+	"disk":      diskCache.get,
+	"network":   guarded("network", collectNetwork),
+	"uptime":    guarded("uptime", collectUptime),
+	"metrics":   func() (any, error) { return metricsSnapshot(), nil },
+	"endpoints": func() (any, error) { return registeredEndpoints(), nil },
+}
+
+// dashboardDataHandler returns everything the dashboard renders in a single
+// response, so each refresh is one request. It is written in the shape the
+// dashboard reads, without the WithEnvelope wrapper.
+func dashboardDataHandler(c *gin.Context) {
+	setCacheControl(c)
+	c.JSON(http.StatusOK, cleanDashboardData())
+}
+
+// dashboardData collects the sections whose endpoints are enabled
+func dashboardData() gin.H {
+	var names []string
+	for name, endpoint := range dashboardSections {
+		if !cfg().disabled[endpoint] {
+			names = append(names, name)
+		}
+	}
+	return runAggregate(names, dashboardCollectors)
+}
+
+// cleanDashboardData is dashboardData prepared for encoding outside respond:
+// percentages rounded and non-finite values replaced
+func cleanDashboardData() any {
+	data, _ := replaceNonFinite(roundPercents(dashboardData(), false))
+	return data
 }
@@ -0,0 +1,63 @@
+package osinfo
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStaticHandlerRejectsTraversal(t *testing.T) {
+	r := newTestEngine(t, "/os")
+
+	for _, target := range []string{
+		"/os/static/../../etc/passwd",
+		"/os/static/..%2f..%2fetc%2fpasswd",
+		"/os/static/%2e%2e/%2e%2e/etc/passwd",
+		"/os/static/templates/..%2f..%2f..%2fetc%2fpasswd",
+		"/os/static/..%5c..%5cetc%5cpasswd",
+		"/os/static//etc/passwd",
+		"/os/static/C:%5cWindows%5cwin.ini",
+	} {
+		w := get(r, target)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", target, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "root:") {
+			t.Errorf("GET %s leaked /etc/passwd", target)
+		}
+	}
+
+	if w := get(r, "/os/static/templates/missing.css"); w.Code != http.StatusNotFound {
+		t.Errorf("GET missing asset = %d, want 404", w.Code)
+	}
+	if w := get(r, "/os/static/templates/dashboard.html"); w.Code != http.StatusOK {
+		t.Errorf("GET embedded asset = %d, want 200", w.Code)
+	}
+}
+
+func TestCustomDashboardPath(t *testing.T) {
+	tests := []struct {
+		prefix, dashboardPath, page, dataURL string
+	}{
+		{"/os", "ui/overview/", "/os/ui/overview", "/os/dashboard-data"},
+		{"", "/ui", "/ui", "/dashboard-data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			r := newTestEngine(t, tt.prefix, WithDashboardPath(tt.dashboardPath))
+
+			w := get(r, tt.page)
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+				t.Fatalf("GET %s = %d %q, want 200 HTML", tt.page, w.Code, w.Header().Get("Content-Type"))
+			}
+			// html/template escapes the slashes inside the script
+			want := `dataURL = "` + strings.ReplaceAll(tt.dataURL, "/", `\/`) + `"`
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("page does not contain %s", want)
+			}
+			if w := get(r, tt.dataURL); w.Code != http.StatusOK {
+				t.Errorf("GET %s = %d, want 200", tt.dataURL, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package osinfo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dimension is a WithDimension breakdown of the request metrics
+type dimension struct {
+	name    string
+	extract func(*gin.Context) string
+}
+
+// dimensionStats holds the per-value request stats of every dimension. Each
+// dimension tracks at most cfg().maxTrackedRoutes values; later values are
+// counted under otherRoute like overflowing routes.
+var dimensionStats = struct {
+	mu     sync.Mutex
+	values map[string]map[string]*RouteStats
+}{values: make(map[string]map[string]*RouteStats)}
+
+// recordDimensions accounts a finished request against the value each
+// dimension extracts from c, counted weight times. Empty values are not
+// counted.
+func recordDimensions(c *gin.Context, elapsed time.Duration, size, weight int64) {
+	for _, d := range cfg().dimensions {
+		value := d.extract(c)
+		if value == "" {
+			continue
+		}
+		value = dimensionValue(d.name, value, elapsed, size, weight)
+		observeDimension(d.name, value, elapsed.Seconds())
+	}
+}
+
+// dimensionValue records the request under name and value and returns the
+// key it was kept under
+func dimensionValue(name, value string, elapsed time.Duration, size, weight int64) string {
+	dimensionStats.mu.Lock()
+	defer dimensionStats.mu.Unlock()
+
+	values := dimensionStats.values[name]
+	if values == nil {
+		values = make(map[string]*RouteStats)
+		dimensionStats.values[name] = values
+	}
+	rs, ok := values[value]
+	if !ok {
+		if trackedValues(values) >= cfg().maxTrackedRoutes {
+			value = otherRoute
+			rs = values[value]
+		}
+		if rs == nil {
+			rs = &RouteStats{}
+			values[value] = rs
+		}
+	}
+	rs.Count += weight
+	rs.TotalResponseTime += elapsed.Milliseconds() * weight
+	if size > 0 {
+		rs.Bytes += size * weight
+	}
+	return value
+}
+
+// trackedValues counts the values with their own entry, leaving out
+// otherRoute
+func trackedValues(values map[string]*RouteStats) int {
+	n := len(values)
+	if _, ok := values[otherRoute]; ok {
+		n--
+	}
+	return n
+}
+
+// dimensionsSnapshot copies the stats as dimension -> value -> stats
+func dimensionsSnapshot() map[string]map[string]RouteStats {
+	dimensionStats.mu.Lock()
+	defer dimensionStats.mu.Unlock()
+
+	out := make(map[string]map[string]RouteStats, len(cfg().dimensions))
+	for _, d := range cfg().dimensions {
+		values := make(map[string]RouteStats, len(dimensionStats.values[d.name]))
+		for v, rs := range dimensionStats.values[d.name] {
+			values[v] = *rs
+		}
+		out[d.name] = values
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package osinfo
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskIOPrev is the sample the next /diskio call computes its rates against
+var diskIOPrev struct {
+	mu       sync.Mutex
+	at       time.Time
+	counters map[string]disk.IOCountersStat
+}
+
+// diskIOHandler reports cumulative IO counters per device along with read
+// and write throughput and IOPS since the previous call. The first call has
+// nothing to compare against and reports zero rates with no_prior_sample.
+func diskIOHandler(c *gin.Context) {
+	writeCollected(c, "diskio", collectDiskIO)
+}
+
+func collectDiskIO() (any, error) {
+	counters, err := sys.DiskIOCounters()
+	if err != nil {
+		return nil, err
+	}
+	now := clk.Now()
+
+	diskIOPrev.mu.Lock()
+	prev, prevAt := diskIOPrev.counters, diskIOPrev.at
+	diskIOPrev.counters, diskIOPrev.at = counters, now
+	diskIOPrev.mu.Unlock()
+
+	elapsed := now.Sub(prevAt).Seconds()
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	devices := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		cur := counters[name]
+		var readBps, writeBps, readIOPS, writeIOPS float64
+		if p, ok := prev[name]; ok && elapsed > 0 {
+			readBps = counterRate(p.ReadBytes, cur.ReadBytes, elapsed)
+			writeBps = counterRate(p.WriteBytes, cur.WriteBytes, elapsed)
+			readIOPS = counterRate(p.ReadCount, cur.ReadCount, elapsed)
+			writeIOPS = counterRate(p.WriteCount, cur.WriteCount, elapsed)
+		}
+		devices = append(devices, gin.H{
+			"device":              name,
+			"read_bytes":          cur.ReadBytes,
+			"write_bytes":         cur.WriteBytes,
+			"read_count":          cur.ReadCount,
+			"write_count":         cur.WriteCount,
+			"read_bytes_per_sec":  readBps,
+			"write_bytes_per_sec": writeBps,
+			"read_iops":           readIOPS,
+			"write_iops":          writeIOPS,
+		})
+	}
+
+	out := gin.H{"devices": devices}
+	if prev == nil {
+		out["no_prior_sample"] = true
+	} else {
+		out["interval_seconds"] = elapsed
+	}
+	return out, nil
+}
+
+// counterRate is the per-second increase of a cumulative counter. A counter
+// that went backwards (device reset or replaced) yields 0.
+func counterRate(before, after uint64, seconds float64) float64 {
+	if after < before {
+		return 0
+	}
+	return float64(after-before) / seconds
+}
@@ -0,0 +1,82 @@
+package osinfo
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// errMountTimeout is reported for a mount whose usage didn't arrive within
+// the WithDiskUsageTimeout deadline
+var errMountTimeout = errors.New("timed out reading filesystem usage")
+
+// errMountPending is reported while an earlier, timed-out probe of the same
+// mount is still blocked
+var errMountPending = errors.New("an earlier usage probe of this mount has not returned")
+
+// networkFstypes are the filesystem types served over the network, which can
+// block indefinitely when the server goes away
+var networkFstypes = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smbfs": true, "smb3": true,
+	"9p": true, "afs": true, "ceph": true, "glusterfs": true, "lustre": true,
+	"davfs": true, "sshfs": true, "fuse": true,
+}
+
+// isNetworkFS reports whether fstype is a network filesystem. FUSE mounts
+// (fuse.sshfs, fuse.s3fs, ...) count as network ones, except fuseblk which
+// is backed by a local block device.
+func isNetworkFS(fstype string) bool {
+	return networkFstypes[fstype] || strings.HasPrefix(fstype, "fuse.")
+}
+
+// mountProbes tracks the mounts whose statfs is still running after its
+// caller gave up, so a hung mount costs one goroutine rather than one per
+// request
+var mountProbes = struct {
+	mu      sync.Mutex
+	pending map[string]bool
+}{pending: make(map[string]bool)}
+
+// mountUsage reads the usage of the filesystem at path, giving up after the
+// WithDiskUsageTimeout deadline. statfs can't be cancelled, so on timeout the
+// call is left running in the background and later probes of the same mount
+// fail fast until it returns.
+func mountUsage(path string) (*disk.UsageStat, error) {
+	timeout := cfg().diskUsageTimeout
+	if timeout <= 0 {
+		return sys.DiskUsage(path)
+	}
+
+	mountProbes.mu.Lock()
+	if mountProbes.pending[path] {
+		mountProbes.mu.Unlock()
+		return nil, errMountPending
+	}
+	mountProbes.pending[path] = true
+	mountProbes.mu.Unlock()
+
+	type result struct {
+		usage *disk.UsageStat
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		u, err := sys.DiskUsage(path)
+		mountProbes.mu.Lock()
+		delete(mountProbes.pending, path)
+		mountProbes.mu.Unlock()
+		done <- result{u, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.usage, r.err
+	case <-timer.C:
+		return nil, errMountTimeout
+	}
+}
@@ -0,0 +1,60 @@
+package osinfo
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// The error rate window covers one minute in 5-second buckets
+const (
+	errorBucketWidth = 5 * time.Second
+	errorBuckets     = 12
+)
+
+// errorWindow counts requests and 5xx responses over the last minute. Each
+// bucket is tagged with the 5-second epoch it counts; a bucket from an older
+// epoch is reset by the first request that reuses it. The counters are
+// atomic so recording takes no lock; a request racing with a reset can be
+// lost, which is fine for an alerting rate.
+type errorWindow struct {
+	buckets [errorBuckets]errorBucket
+}
+
+type errorBucket struct {
+	epoch  atomic.Int64
+	total  atomic.Int64
+	errors atomic.Int64
+}
+
+func (w *errorWindow) add(now time.Time, status int, weight int64) {
+	epoch := now.UnixNano() / int64(errorBucketWidth)
+	b := &w.buckets[epoch%errorBuckets]
+	if old := b.epoch.Load(); old != epoch && b.epoch.CompareAndSwap(old, epoch) {
+		b.total.Store(0)
+		b.errors.Store(0)
+	}
+	b.total.Add(weight)
+	if status >= 500 {
+		b.errors.Add(weight)
+	}
+}
+
+// rate returns the share of 5xx responses in the last minute, 0 when there
+// were no requests
+func (w *errorWindow) rate(now time.Time) float64 {
+	epoch := now.UnixNano() / int64(errorBucketWidth)
+	var total, errs int64
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if e := b.epoch.Load(); e > epoch-errorBuckets && e <= epoch {
+			total += b.total.Load()
+			errs += b.errors.Load()
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(errs) / float64(total)
+}
+
+var recentErrors errorWindow
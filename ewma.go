@@ -0,0 +1,50 @@
+package osinfo
+
+import (
+	"math"
+	"time"
+)
+
+// decayingMean is an exponentially time-decayed mean: each observation's
+// weight halves every half-life, so the mean tracks recent values and the
+// sums stay bounded however long the server runs.
+type decayingMean struct {
+	sum    float64
+	weight float64
+	at     time.Time
+}
+
+// decay ages the sums to now
+func (d *decayingMean) decay(now time.Time, halfLife time.Duration) {
+	if !d.at.IsZero() && now.After(d.at) {
+		f := math.Exp2(-float64(now.Sub(d.at)) / float64(halfLife))
+		d.sum *= f
+		d.weight *= f
+	}
+	d.at = now
+}
+
+func (d *decayingMean) add(now time.Time, halfLife time.Duration, value, weight float64) {
+	d.decay(now, halfLife)
+	d.sum += value * weight
+	d.weight += weight
+}
+
+// decayedLatencyMs combines the decaying latency means of all shards into
+// one average in milliseconds
+func (m *Metrics) decayedLatencyMs() float64 {
+	now := clk.Now()
+	var sum, weight float64
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		s.latency.decay(now, cfg().latencyHalfLife)
+		sum += s.latency.sum
+		weight += s.latency.weight
+		s.mu.Unlock()
+	}
+	if weight == 0 {
+		return 0
+	}
+	return sum / weight
+}
@@ -8,7 +8,8 @@ import (
 )
 
 func main() {
-	r := gin.Default()
+	r := gin.New()
+	r.Use(osinfo.QuietLogging(), gin.Recovery())
 
 	// Register under /os
 	osinfo.RegisterRoutes(r, "")
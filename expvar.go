@@ -0,0 +1,30 @@
+package osinfo
+
+import (
+	"expvar"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var expvarOnce sync.Once
+
+// publishExpvar exposes the request metrics under the "osinfo" expvar key.
+// expvar names are process-global, so this only ever runs once.
+func publishExpvar() {
+	expvarOnce.Do(func() {
+		expvar.Publish("osinfo", expvar.Func(func() any {
+			return map[string]any{
+				"total_requests":        metrics.TotalRequests.Load(),
+				"total_response_ms":     metrics.TotalResponseTime.Load(),
+				"in_flight":             metrics.InFlight.Load(),
+				"status_codes":          metrics.StatusCodes(),
+				"server_uptime_seconds": since(metrics.StartTime).Seconds(),
+			}
+		}))
+	})
+}
+
+// expvarHandler serves every published expvar variable, including the
+// process command line and memory stats, so it sits behind requireAuth
+var expvarHandler = gin.WrapH(expvar.Handler())
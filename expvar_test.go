@@ -0,0 +1,24 @@
+package osinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpvarRequiresAuth(t *testing.T) {
+	r := newTestEngine(t, "/os", WithExpvar(), WithBasicAuth("ops", "secret"))
+
+	if w := get(r, "/os/debug/vars"); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /os/debug/vars without credentials = %d, want 401", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/os/debug/vars", nil)
+	req.SetBasicAuth("ops", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"osinfo"`) {
+		t.Errorf("GET /os/debug/vars with credentials = %d, want 200 with the osinfo key", w.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package osinfo
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+var customGauges = struct {
+	mu     sync.RWMutex
+	gauges map[string]func() float64
+}{gauges: make(map[string]func() float64)}
+
+// RegisterGauge adds an application gauge, such as a queue depth, to the
+// "custom" object of /metrics and the dashboard. fn is called on every
+// request for those, so it must be cheap and safe for concurrent use.
+// Registering a name again replaces its function.
+func RegisterGauge(name string, fn func() float64) {
+	customGauges.mu.Lock()
+	defer customGauges.mu.Unlock()
+	customGauges.gauges[name] = fn
+}
+
+// sampleGauges reads every custom gauge. A gauge that panics is logged and
+// left out rather than failing the response.
+func sampleGauges() map[string]float64 {
+	customGauges.mu.RLock()
+	names := make([]string, 0, len(customGauges.gauges))
+	fns := make(map[string]func() float64, len(customGauges.gauges))
+	for name, fn := range customGauges.gauges {
+		names = append(names, name)
+		fns[name] = fn
+	}
+	customGauges.mu.RUnlock()
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+	out := make(map[string]float64, len(names))
+	for _, name := range names {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("osinfo: gauge %q panicked: %v", name, r)
+				}
+			}()
+			out[name] = fns[name]()
+		}()
+	}
+	return out
+}
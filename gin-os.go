@@ -1,72 +1,433 @@
 package osinfo
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
 	"net/http"
 	"os"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	cpu "github.com/shirou/gopsutil/v3/cpu"
-	disk "github.com/shirou/gopsutil/v3/disk"
-	host "github.com/shirou/gopsutil/v3/host"
-	mem "github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/net"
 )
 
-// Metrics tracks request statistics
+// Metrics tracks request statistics. The counters are updated atomically;
+// status codes and per-route stats are spread over shards, each with its own
+// lock, so concurrent requests rarely contend.
 type Metrics struct {
-	mu                sync.RWMutex
-	TotalRequests     int64
-	TotalResponseTime int64
-	StatusCodes       map[int]int64
+	TotalRequests     atomic.Int64
+	TodayRequests     atomic.Int64
+	TotalResponseTime atomic.Int64
+	InFlight          atomic.Int64
 	StartTime         time.Time
+
+	shards        [metricShards]metricShard
+	trackedRoutes atomic.Int64
+	// totalMicros backs the all-time average; TotalResponseTime stays in
+	// whole milliseconds, which rounds fast requests down to zero
+	totalMicros atomic.Int64
+}
+
+// metricShards is the number of shards; a power of two so the hash can be
+// masked
+const metricShards = 16
+
+type metricShard struct {
+	mu          sync.Mutex
+	statusCodes map[int]int64
+	routes      map[string]*RouteStats
+	latencies   map[string]*latencyWindow
+	latency     decayingMean
 }
 
+// RouteStats tracks request statistics for a single route
+type RouteStats struct {
+	Count             int64 `json:"count"`
+	TotalResponseTime int64 `json:"total_response_time_ms"`
+	Bytes             int64 `json:"bytes"`
+}
+
+// otherRoute collects routes seen after the tracked-route limit is reached
+const otherRoute = "<other>"
+
 var metrics = &Metrics{
-	StatusCodes: make(map[int]int64),
-	StartTime:   time.Now(),
+	StartTime: clk.Now(),
+}
+
+// shardFor picks the shard for route with FNV-1a, so a route always lands on
+// the same shard
+func (m *Metrics) shardFor(route string) *metricShard {
+	h := uint32(2166136261)
+	for i := 0; i < len(route); i++ {
+		h ^= uint32(route[i])
+		h *= 16777619
+	}
+	return &m.shards[h&(metricShards-1)]
+}
+
+// record accounts a finished request against its route and status code,
+// counted weight times when requests are sampled
+func (m *Metrics) record(route string, status int, elapsed time.Duration, size, weight int64) {
+	s := m.shardFor(route)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.routes == nil {
+		s.statusCodes = make(map[int]int64)
+		s.routes = make(map[string]*RouteStats)
+		s.latencies = make(map[string]*latencyWindow)
+	}
+	s.statusCodes[status] += weight
+	s.latency.add(clk.Now(), cfg().latencyHalfLife, float64(elapsed)/float64(time.Millisecond), float64(weight))
+	rs, route := m.routeStats(s, route)
+	lw := s.latencies[route]
+	if lw == nil {
+		lw = &latencyWindow{}
+		s.latencies[route] = lw
+	}
+	lw.add(elapsed)
+
+	durationMs := elapsed.Milliseconds()
+	rs.Count += weight
+	rs.TotalResponseTime += durationMs * weight
+	if size > 0 {
+		rs.Bytes += size * weight
+	}
+}
+
+// routeStats returns the stats entry for route in s and the key it is kept
+// under, bucketing new routes into otherRoute once cfg().maxTrackedRoutes is
+// reached across all shards. s.mu must be held.
+func (m *Metrics) routeStats(s *metricShard, route string) (*RouteStats, string) {
+	if rs, ok := s.routes[route]; ok {
+		return rs, route
+	}
+	if m.trackedRoutes.Add(1) > int64(cfg().maxTrackedRoutes) {
+		m.trackedRoutes.Add(-1)
+		route = otherRoute
+		if rs, ok := s.routes[route]; ok {
+			return rs, route
+		}
+	}
+	rs := &RouteStats{}
+	s.routes[route] = rs
+	return rs, route
+}
+
+// StatusCodes returns the request count per status code, merged across
+// shards. It replaces the StatusCodes map field.
+func (m *Metrics) StatusCodes() map[int]int64 {
+	codes := make(map[int]int64)
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		for code, n := range s.statusCodes {
+			codes[code] += n
+		}
+		s.mu.Unlock()
+	}
+	return codes
+}
+
+// Routes returns the per-route stats, merged across shards. otherRoute can
+// appear in several shards and is summed. It replaces the Routes map field.
+func (m *Metrics) Routes() map[string]RouteStats {
+	routes := make(map[string]RouteStats)
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		for route, rs := range s.routes {
+			merged := routes[route]
+			merged.Count += rs.Count
+			merged.TotalResponseTime += rs.TotalResponseTime
+			merged.Bytes += rs.Bytes
+			routes[route] = merged
+		}
+		s.mu.Unlock()
+	}
+	return routes
 }
 
-// RegisterRoutes registers all OS endpoints and dashboard
-func RegisterRoutes(r gin.IRouter, prefix string) {
+// RegisterRoutes registers all OS endpoints and dashboard under prefix on r.
+// The prefix is normalized to a single leading slash and no trailing slash,
+// so "os", "/os" and "/os/" all give /os/health, while "" and "/" put the
+// endpoints at the root of r (/health).
+//
+// The metrics middleware is attached to r itself, so it measures every route
+// registered on r (or its sub-groups) after this call; the osinfo routes are
+// excluded. When r is a *gin.RouterGroup such as r.Group("/admin"), both the
+// middleware and the routes stay scoped to that group, and prefix "/os" puts
+// the endpoints at /admin/os/....
+//
+// RegisterRoutes may be called again with another prefix to serve the same
+// endpoints in several places, e.g. /internal/os and /admin/os. The metrics
+// middleware is installed once per router, and requests passing through more
+// than one instance (nested groups) are counted once. Options accumulate
+// across calls. A call may run while engines registered earlier are serving;
+// they switch to the new configuration as a whole.
+func RegisterRoutes(r gin.IRouter, prefix string, opts ...Option) {
+	registerMu.Lock()
+	defer registerMu.Unlock()
+
+	// options apply to a copy, swapped in whole, so requests already being
+	// served never see a half-applied configuration
+	next := cfg().clone()
+	for _, opt := range opts {
+		opt(next)
+	}
+	// Config.Prefix applies to this call only; cfg outlives it
+	if next.prefix != "" {
+		prefix, next.prefix = next.prefix, ""
+	}
+	current.Store(next)
+	prefix = normalizePrefix(prefix)
+	registerPrometheus(cfg())
+	if templateErr != nil {
+		log.Printf("osinfo: dashboard disabled: %v", templateErr)
+	}
+	primeCPUSampler()
+
+	if _, err := mountUsage(cfg().rootMount); err != nil {
+		log.Printf("osinfo: root mount %q is not usable: %v", cfg().rootMount, err)
+	}
 
-	// Middleware for metrics
-	r.Use(metricsMiddleware())
+	// Middleware for metrics, once per router
+	if !metricsInstalled[r] {
+		metricsInstalled[r] = true
+		r.Use(metricsMiddleware(true))
+	}
 
-	grp := r.Group(prefix)
+	grp := &osinfoGroup{RouterGroup: r.Group(prefix), methods: make(map[string][]string)}
 	grp.GET("/health", healthHandler)
+	grp.GET("/readyz", readyzHandler)
+	grp.GET("/status", statusHandler)
 	grp.GET("/info", infoHandler)
 	grp.GET("/uptime", uptimeHandler)
 	grp.GET("/mem", memHandler)
 	grp.GET("/cpu", cpuHandler)
+	grp.GET("/cpu/alloc", cpuAllocHandler)
+	grp.GET("/cpu/times", cpuTimesHandler)
 	grp.GET("/disk", diskHandler)
-	grp.GET("/env", envHandler)
+	if cfg().tieredEnv {
+		grp.GET("/env", envHandler)
+	} else {
+		grp.GET("/env", requireAuth(), envHandler)
+	}
 	grp.GET("/metrics", metricsHandler)
+	grp.GET("/metrics/csv", metricsCSVHandler)
 	grp.GET("/server-uptime", serverUptimeHandler)
 
 	// Prometheus handler
-	grp.GET("/gui-metrics", gin.WrapH(promhttp.Handler()))
+	grp.GET("/gui-metrics", gin.WrapH(promHandler()))
+	grp.GET("/gui-metrics/json", promJSONHandler)
 
 	// Dashboard UI
-	grp.GET("/dashboard", serveDashboard)
+	grp.GET(cfg().dashboardPath, securityHeadersMiddleware(), serveDashboard)
+	grp.GET(path.Join(cfg().dashboardPath, "export"), securityHeadersMiddleware(), exportDashboardHandler)
+	grp.GET("/dashboard-data", dashboardDataHandler)
+	grp.GET("/dashboard-stream", dashboardStreamHandler)
 
 	// Static files
-	grp.GET("/static/*filepath", staticHandler)
+	grp.GET("/static/*filepath", securityHeadersMiddleware(), staticHandler)
 
+	grp.GET("/diskio", diskIOHandler)
 	grp.GET("/network", networkHandler)
+	grp.GET("/network/interfaces", networkInterfacesHandler)
+	grp.GET("/collectors", collectorsHandler)
+	grp.GET("/processes", processesHandler)
+	grp.GET("/load", loadHandler)
+	grp.GET("/batch", batchHandler)
+	grp.GET("/threads", threadsHandler)
+	grp.GET("/sched", schedHandler)
+	grp.GET("/runtime", runtimeHandler)
+	grp.GET("/kernel", kernelHandler)
+	grp.GET("/users", usersHandler)
+	grp.GET("/identity", identityHandler)
+	grp.GET("/pressure", pressureHandler)
+	grp.GET("/version", versionHandler)
+	grp.GET("/routes", routesHandler)
+	grp.POST("/snapshot", snapshotHandler)
+	grp.GET("/diff", diffHandler)
+
+	for _, e := range cfg().customEndpoints {
+		grp.register(http.MethodGet, e.path, e.description, true, e.handler)
+	}
+
+	if cfg().alertWebhook != "" && cfg().alertInterval > 0 {
+		url, interval := cfg().alertWebhook, cfg().alertInterval
+		startBackground("alerter", func(stop <-chan struct{}) {
+			runAlerter(url, interval, stop)
+		})
+	}
+
+	if cfg().profiling && hasCredentials("profiling endpoints") {
+		grp.GET("/prof/heap", requireAuth(), heapProfileHandler)
+		grp.GET("/prof/goroutine", requireAuth(), goroutineDumpHandler)
+	}
+
+	if cfg().statsdAddr != "" {
+		addr, prefix := cfg().statsdAddr, cfg().statsdPrefix
+		startBackground("statsd", func(stop <-chan struct{}) {
+			runStatsD(addr, prefix, stop)
+		})
+	}
+
+	if cfg().requestLogSize > 0 && hasCredentials("/requests and /requests/stream") {
+		if ring := requestLog.Load(); ring == nil {
+			requestLog.Store(&requestRing{records: make([]requestRecord, cfg().requestLogSize)})
+		} else {
+			ring.resize(cfg().requestLogSize)
+		}
+		grp.GET("/requests", requireAuth(), requestsHandler)
+		grp.GET("/requests/stream", requireAuth(), requestsStreamHandler)
+	}
+
+	if cfg().dailyReset != nil {
+		loc := cfg().dailyReset
+		startBackground("daily-reset", func(stop <-chan struct{}) {
+			runDailyReset(loc, stop)
+		})
+	}
+
+	if cfg().goroutineInterval > 0 {
+		if goroutineHistory == nil {
+			goroutineHistory = &goroutineRing{samples: make([]goroutineSample, cfg().goroutineSamples)}
+		}
+		ring, interval := goroutineHistory, cfg().goroutineInterval
+		startBackground("goroutines", func(stop <-chan struct{}) {
+			runGoroutineSampler(ring, interval, stop)
+		})
+		grp.GET("/goroutines/history", goroutineHistoryHandler)
+	}
+
+	if cfg().expvar {
+		publishExpvar()
+		grp.GET("/debug/vars", requireAuth(), expvarHandler)
+	}
 
+	grp.rejectOtherMethods()
 }
 
+// healthHandler is the liveness probe. With WithEmptyHealthBody it answers
+// 204 without a body, and with WithHealthDetails it adds healthDetails.
 func healthHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	if cfg().emptyHealthBody {
+		setCacheControl(c)
+		c.Status(http.StatusNoContent)
+		return
+	}
+	out := gin.H{"status": "ok"}
+	if cfg().healthDetails {
+		healthDetails(out)
+	}
+	respond(c, http.StatusOK, out)
+}
+
+// healthDetails adds the WithHealthDetails summary to out. Failed reads are
+// skipped so liveness never depends on them.
+func healthDetails(out gin.H) {
+	out["uptime_seconds"] = since(metrics.StartTime).Seconds()
+	out["total_requests"] = metrics.TotalRequests.Load()
+	if p, err := instantCPUPercent(&healthCPU); err == nil && len(p) > 0 {
+		out["cpu_percent"] = p[0]
+	}
+	if m, err := sys.VirtualMemory(); err == nil {
+		out["mem_used_percent"] = m.UsedPercent
+	}
 }
 
+// infoHandler returns a curated subset of the host info, or with ?full=true
+// everything gopsutil reports, including HostID, Procs and BootTime
 func infoHandler(c *gin.Context) {
-	h, _ := host.Info()
-	c.JSON(http.StatusOK, gin.H{
+	if full, _ := strconv.ParseBool(c.Query("full")); full {
+		writeCached(c, fullInfoCache)
+		return
+	}
+	writeCached(c, infoCache)
+}
+
+func collectFullInfo() (any, error) {
+	h, err := sys.HostInfo()
+	if err != nil && h == nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func uptimeHandler(c *gin.Context) {
+	writeCollected(c, "uptime", collectUptime)
+}
+
+func memHandler(c *gin.Context) {
+	writeCollected(c, "mem", collectMem)
+}
+
+// diskHandler reports usage for every partition, or with ?path=/data only
+// for the filesystem containing that path, without enumerating partitions
+func diskHandler(c *gin.Context) {
+	if p := c.Query("path"); p != "" {
+		diskPathHandler(c, p)
+		return
+	}
+	writeCached(c, diskCache)
+}
+
+// diskPathHandler bypasses the collector bookkeeping so that a mistyped path
+// can't trip the disk circuit breaker
+func diskPathHandler(c *gin.Context, p string) {
+	usage, err := mountUsage(p)
+	if errors.Is(err, os.ErrNotExist) {
+		respond(c, http.StatusNotFound, gin.H{"error": "no such path: " + p})
+		return
+	}
+	if errors.Is(err, errMountTimeout) || errors.Is(err, errMountPending) {
+		respond(c, http.StatusGatewayTimeout, gin.H{"error": err.Error(), "path": p})
+		return
+	}
+	if err != nil {
+		collectorError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, gin.H{
+		"path":        usage.Path,
+		"fstype":      usage.Fstype,
+		"total":       usage.Total,
+		"free":        usage.Free,
+		"used":        usage.Used,
+		"usedPercent": usage.UsedPercent,
+	})
+}
+
+// writeCollected runs the named collector and writes its result or error
+func writeCollected(c *gin.Context, name string, collect func() (any, error)) {
+	data, err := runCollector(name, collect)
+	writeResult(c, data, err)
+}
+
+// writeResult writes a collector result, or its error
+func writeResult(c *gin.Context, data any, err error) {
+	if err != nil {
+		collectorError(c, err)
+		return
+	}
+	respond(c, http.StatusOK, data)
+}
+
+func collectInfo() (any, error) {
+	h, err := sys.HostInfo()
+	if err != nil && h == nil {
+		return nil, err
+	}
+	return gin.H{
 		"hostname":        h.Hostname,
 		"uptime":          h.Uptime,
 		"platform":        h.Platform,
@@ -74,50 +435,90 @@ func infoHandler(c *gin.Context) {
 		"platformVersion": h.PlatformVersion,
 		"kernelVersion":   h.KernelVersion,
 		"architecture":    h.KernelArch,
-	})
+	}, nil
 }
 
-func uptimeHandler(c *gin.Context) {
-	u, err := host.Uptime()
+// collectUptime reports host uptime together with the server's own uptime.
+// uptime_seconds is kept for compatibility with older clients.
+func collectUptime() (any, error) {
+	u, err := sys.Uptime()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
+	}
+	out := gin.H{
+		"uptime_seconds":        u,
+		"breakdown":             uptimeBreakdown(float64(u)),
+		"host_uptime_seconds":   u,
+		"server_uptime_seconds": since(metrics.StartTime).Seconds(),
+		"server_start_time":     metrics.StartTime,
 	}
-	c.JSON(http.StatusOK, gin.H{"uptime_seconds": u})
+	if boot, err := sys.BootTime(); err == nil {
+		out["host_boot_time"] = time.Unix(int64(boot), 0)
+	}
+	return out, nil
 }
 
-func memHandler(c *gin.Context) {
-	m, err := mem.VirtualMemory()
+// uptimeBreakdown splits a duration in seconds into whole days, hours,
+// minutes and seconds
+func uptimeBreakdown(seconds float64) gin.H {
+	total := int64(seconds)
+	return gin.H{
+		"days":    total / 86400,
+		"hours":   total % 86400 / 3600,
+		"minutes": total % 3600 / 60,
+		"seconds": total % 60,
+	}
+}
+
+func collectMem() (any, error) {
+	m, err := sys.VirtualMemory()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
-	c.JSON(http.StatusOK, gin.H{
+	out := gin.H{
 		"total":       m.Total,
 		"available":   m.Available,
 		"used":        m.Used,
 		"usedPercent": m.UsedPercent,
-	})
-}
-
-func cpuHandler(c *gin.Context) {
-	percent, err := cpu.Percent(500*time.Millisecond, false)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		"free":        m.Free,
+	}
+	// Cache figures explain the gap between free and available. They are
+	// only reported where the platform provides them (mainly Linux).
+	for k, v := range map[string]uint64{
+		"buffers":      m.Buffers,
+		"cached":       m.Cached,
+		"shared":       m.Shared,
+		"sreclaimable": m.Sreclaimable,
+	} {
+		if v > 0 {
+			out[k] = v
+		}
 	}
-	c.JSON(http.StatusOK, gin.H{"cpu_percent": percent})
+	return out, nil
 }
 
-func diskHandler(c *gin.Context) {
-	parts, err := disk.Partitions(false)
+func collectDisk() (any, error) {
+	parts, err := reportedPartitions()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
 	out := []gin.H{}
 	for _, p := range parts {
-		usage, err := disk.Usage(p.Mountpoint)
+		if p.Opts == nil {
+			p.Opts = []string{}
+		}
+		usage, err := mountUsage(p.Mountpoint)
+		if errors.Is(err, errMountTimeout) || errors.Is(err, errMountPending) {
+			// report hung mounts rather than hiding them
+			out = append(out, gin.H{
+				"device":     p.Device,
+				"mountpoint": p.Mountpoint,
+				"fstype":     p.Fstype,
+				"opts":       p.Opts,
+				"error":      err.Error(),
+			})
+			continue
+		}
 		if err != nil {
 			continue
 		}
@@ -129,115 +530,420 @@ func diskHandler(c *gin.Context) {
 			"free":        usage.Free,
 			"used":        usage.Used,
 			"usedPercent": usage.UsedPercent,
+			"opts":        p.Opts,
+			"readonly":    isReadOnly(p.Opts),
 		})
 	}
-	c.JSON(http.StatusOK, out)
+	if cfg().maxPartitions <= 0 {
+		return out, nil
+	}
+	total := len(out)
+	if total > cfg().maxPartitions {
+		out = out[:cfg().maxPartitions]
+	}
+	return gin.H{
+		"partitions": out,
+		"total":      total,
+		"truncated":  total > cfg().maxPartitions,
+	}, nil
+}
+
+// reportedPartitions lists the partitions minus the filesystem types
+// excluded with WithExcludeFstypes, network filesystems when
+// WithNetworkFilesystems(false) is set and, with WithPhysicalDisksOnly,
+// anything not backed by a block device
+func reportedPartitions() ([]disk.PartitionStat, error) {
+	parts, err := sys.Partitions(false)
+	if err != nil || (len(cfg().excludeFstypes) == 0 && !cfg().physicalDisksOnly && !cfg().excludeNetworkFS) {
+		return parts, err
+	}
+	kept := parts[:0]
+	for _, p := range parts {
+		if cfg().excludeFstypes[p.Fstype] || (cfg().physicalDisksOnly && !isPhysical(p)) ||
+			(cfg().excludeNetworkFS && isNetworkFS(p.Fstype)) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, nil
+}
+
+// physicalFstypes are the filesystem types that live on a block device
+var physicalFstypes = map[string]bool{
+	"ext2": true, "ext3": true, "ext4": true, "xfs": true, "btrfs": true,
+	"zfs": true, "f2fs": true, "jfs": true, "reiserfs": true,
+	"vfat": true, "exfat": true, "ntfs": true, "ntfs3": true, "fuseblk": true,
+	"apfs": true, "hfs": true, "ufs": true,
+	// Windows reports upper-case names and drive-letter devices
+	"NTFS": true, "FAT32": true, "exFAT": true, "ReFS": true,
+}
+
+// isPhysical is a heuristic for a block-backed filesystem: a /dev/ device (or
+// a Windows drive letter) with a known on-disk filesystem type
+func isPhysical(p disk.PartitionStat) bool {
+	if !physicalFstypes[p.Fstype] {
+		return false
+	}
+	return strings.HasPrefix(p.Device, "/dev/") || (len(p.Device) == 2 && p.Device[1] == ':')
+}
+
+// isReadOnly reports whether the mount options include "ro". A filesystem
+// that flips to read-only usually means the kernel hit IO errors.
+func isReadOnly(opts []string) bool {
+	for _, o := range opts {
+		if o == "ro" {
+			return true
+		}
+	}
+	return false
 }
 
+// envHandler returns the environment. ?prefix=MYAPP_,OTHER_ limits it to
+// variables whose name starts with one of the (case-sensitive) prefixes.
+// With WithTieredEnv, callers without the basic auth credentials get the
+// names only, with redacted values.
 func envHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"env": os.Environ()})
+	env := os.Environ()
+	if q := c.Query("prefix"); q != "" {
+		env = filterEnvPrefix(env, strings.Split(q, ","))
+	}
+	redacted := cfg().tieredEnv && (cfg().authUser == "" || !isAuthenticated(c))
+	if redacted {
+		env = redactEnv(env)
+	}
+	respond(c, http.StatusOK, gin.H{"env": env, "redacted": redacted})
+}
+
+const redactedValue = "[REDACTED]"
+
+func redactEnv(env []string) []string {
+	out := make([]string, len(env))
+	for i, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		out[i] = key + "=" + redactedValue
+	}
+	return out
+}
+
+func filterEnvPrefix(env, prefixes []string) []string {
+	out := []string{}
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		for _, p := range prefixes {
+			if p != "" && strings.HasPrefix(key, p) {
+				out = append(out, kv)
+				break
+			}
+		}
+	}
+	return out
 }
 
 // ===== METRICS =====
-var ignoreMetrics = map[string]bool{
-	"/metrics":       true,
-	"/health":        true,
-	"/info":          true,
-	"/cpu":           true,
-	"/mem":           true,
-	"/disk":          true,
-	"/env":           true,
-	"/server-uptime": true,
-	"/dashboard":     true,
-	"/static":        true,
-}
-
-func shouldIgnore(path string) bool {
-
-	if path == "" {
-		return false
+
+// ownRoutes holds the full paths of the osinfo routes, which are left out of
+// the request statistics
+var ownRoutes = struct {
+	mu    sync.RWMutex
+	paths map[string]bool
+}{paths: make(map[string]bool)}
+
+// isOwnRoute reports whether fullPath is an osinfo route
+func isOwnRoute(fullPath string) bool {
+	ownRoutes.mu.RLock()
+	defer ownRoutes.mu.RUnlock()
+	return ownRoutes.paths[fullPath]
+}
+
+// normalizePrefix returns prefix with one leading slash and no trailing
+// slashes, or "" for the root
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
 	}
+	return "/" + prefix
+}
+
+// osinfoGroup registers routes on a gin group, skipping disabled endpoints,
+// and records their full paths in ownRoutes
+type osinfoGroup struct {
+	*gin.RouterGroup
+	methods map[string][]string
+}
+
+func (g *osinfoGroup) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	g.register(http.MethodGet, relativePath, "", false, handlers...)
+}
+
+func (g *osinfoGroup) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	g.register(http.MethodPost, relativePath, "", false, handlers...)
+}
 
-	ignored := []string{
-		"/",
-		"/metrics",
-		"/gui-metrics",
-		"/health",
-		"/info",
-		"/cpu",
-		"/mem",
-		"/disk",
-		"/env",
-		"/server-uptime",
-		"/dashboard",
-		"/static",
+func (g *osinfoGroup) register(method, relativePath, description string, custom bool, handlers ...gin.HandlerFunc) {
+	if cfg().disabled[relativePath] {
+		return
 	}
+	ownRoutes.mu.Lock()
+	ownRoutes.paths[path.Join(g.BasePath(), relativePath)] = true
+	ownRoutes.mu.Unlock()
+	recordEndpoint(g.BasePath(), relativePath, method, description, custom)
+	if fields, ok := cfg().fields[relativePath]; ok {
+		handlers = append([]gin.HandlerFunc{selectFields(fields)}, handlers...)
+	}
+	g.methods[relativePath] = append(g.methods[relativePath], method)
+	g.RouterGroup.Handle(method, relativePath, handlers...)
+}
 
-	for _, p := range ignored {
-		if strings.HasPrefix(path, p) {
-			return true
+// rejectedMethods get a 405 on osinfo paths that don't handle them
+var rejectedMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// rejectOtherMethods answers methods an osinfo path doesn't handle with 405
+// and an Allow header, rather than gin's default 404. The handlers are added
+// per route, so the engine's HandleMethodNotAllowed setting and NoMethod
+// handlers, and the status of the application's own routes, are left alone.
+// A method the application registered on the path first is skipped.
+func (g *osinfoGroup) rejectOtherMethods() {
+	for relativePath, methods := range g.methods {
+		allow := strings.Join(methods, ", ")
+		handler := func(c *gin.Context) {
+			c.Header("Allow", allow)
+			respond(c, http.StatusMethodNotAllowed, gin.H{"error": "method not allowed"})
+		}
+		for _, m := range rejectedMethods {
+			if !slices.Contains(methods, m) {
+				g.handleUnlessRegistered(m, relativePath, handler)
+			}
 		}
 	}
+}
 
-	return false
+// handleUnlessRegistered adds a route unless gin already has one for the
+// method and path, in which case gin panics and the existing route is kept
+func (g *osinfoGroup) handleUnlessRegistered(method, relativePath string, handler gin.HandlerFunc) {
+	defer func() {
+		if r := recover(); r != nil && !strings.Contains(fmt.Sprint(r), "already registered") {
+			panic(r)
+		}
+	}()
+	g.RouterGroup.Handle(method, relativePath, handler)
 }
 
-func metricsMiddleware() gin.HandlerFunc {
+func shouldIgnore(fullPath string) bool {
+	// Ignore the osinfo endpoints and root "/"
+	return fullPath == "/" || isOwnRoute(fullPath)
+}
+
+// registerMu serializes RegisterRoutes calls
+var registerMu sync.Mutex
+
+// metricsInstalled records the routers that already have the metrics
+// middleware. registerMu guards it.
+var metricsInstalled = map[gin.IRouter]bool{}
+
+// metricsCountedKey marks a request as already measured by an outer
+// instance of the middleware
+const metricsCountedKey = "osinfo.metrics.counted"
+
+// Middleware returns the request metrics middleware for an engine that
+// doesn't mount the osinfo routes itself, e.g. the public app when the
+// endpoints are served with ListenAndServe. It counts every route except
+// "/", including app routes that share a path with an osinfo endpoint
+// elsewhere, such as /users. RegisterRoutes installs its own instance; a
+// request passing through several instances is counted once.
+func Middleware() gin.HandlerFunc {
+	return metricsMiddleware(false)
+}
+
+// metricsMiddleware measures requests; with skipOwn it leaves out the
+// osinfo routes
+func metricsMiddleware(skipOwn bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.GetBool(metricsCountedKey) {
+			c.Next()
+			return
+		}
+		c.Set(metricsCountedKey, true)
 
 		path := c.FullPath()
 
-		// Ignore system/monitoring endpoints (including root "/")
-		if shouldIgnore(path) {
+		// Ignore system/monitoring endpoints
+		if path == "/" || skipOwn && shouldIgnore(path) {
 			c.Next()
 			return
 		}
 
-		start := time.Now()
+		metrics.InFlight.Add(1)
+		start := clk.Now()
 		c.Next()
-		duration := time.Since(start).Milliseconds()
+		elapsed := since(start)
+		duration := elapsed.Milliseconds()
+		metrics.InFlight.Add(-1)
+
+		status := c.Writer.Status()
+		// sampled-out requests skip all per-request bookkeeping
+		weight := sampleWeight(status, elapsed)
+		if weight == 0 {
+			return
+		}
+		observeRequest(c.Request.Method, path, status, elapsed.Seconds())
+		for _, observe := range cfg().observers {
+			observe(c.Request.Method, path, status, elapsed)
+		}
+		if ring := requestLog.Load(); ring != nil {
+			rec := requestRecord{
+				Time:       start,
+				Method:     c.Request.Method,
+				Path:       c.Request.URL.Path,
+				Status:     status,
+				DurationMs: float64(elapsed.Microseconds()) / 1000,
+			}
+			if cfg().ginErrors {
+				if last := c.Errors.Last(); last != nil {
+					rec.Error = last.Error()
+				}
+			}
+			ring.add(rec)
+			publishRequest(rec)
+		}
+		if cfg().slowThreshold > 0 && elapsed > cfg().slowThreshold {
+			cfg().logger.Warn("osinfo: slow request",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"status", status,
+				"duration", elapsed)
+		}
+
+		size := int64(c.Writer.Size())
+		recordDimensions(c, elapsed, size, weight)
+		recentErrors.add(clk.Now(), status, weight)
+		if cfg().ginErrors {
+			recordGinErrors(c.Errors, weight)
+		}
+		metrics.TotalRequests.Add(weight)
+		metrics.TodayRequests.Add(weight)
+		metrics.TotalResponseTime.Add(duration * weight)
+		metrics.totalMicros.Add(elapsed.Microseconds() * weight)
+		metrics.record(c.Request.Method+" "+path, status, elapsed, size, weight)
+	}
+}
 
-		metrics.mu.Lock()
-		metrics.TotalRequests++
-		metrics.TotalResponseTime += duration
-		metrics.StatusCodes[c.Writer.Status()]++
-		metrics.mu.Unlock()
+// sampleWeight decides whether a request is recorded under WithSampleRate and
+// how many requests it stands for: every sampleEvery-th request on average
+// is recorded as sampleEvery requests. Server errors and requests over the
+// slow threshold are always recorded as themselves so they are never sampled
+// away.
+func sampleWeight(status int, elapsed time.Duration) int64 {
+	n := cfg().sampleEvery
+	if n <= 1 || status >= 500 || cfg().slowThreshold > 0 && elapsed > cfg().slowThreshold {
+		return 1
 	}
+	if rand.Uint64N(uint64(n)) != 0 {
+		return 0
+	}
+	return n
 }
 
 func metricsHandler(c *gin.Context) {
-	metrics.mu.RLock()
-	defer metrics.mu.RUnlock()
+	includeSystem := cfg().systemInMetrics
+	if v, err := strconv.ParseBool(c.Query("system")); err == nil {
+		includeSystem = v
+	}
 
-	avg := float64(0)
-	if metrics.TotalRequests > 0 {
-		avg = float64(metrics.TotalResponseTime) / float64(metrics.TotalRequests)
+	resp := metricsSnapshot()
+	if includeSystem {
+		resp["system"] = systemGauges(&gaugesCPU)
 	}
+	respond(c, http.StatusOK, resp)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"total_requests":       metrics.TotalRequests,
-		"avg_response_time_ms": avg,
-		"status_codes":         metrics.StatusCodes,
-	})
+// metricsSnapshot reports the request metrics. avg_response_time_ms is a
+// decaying average (see WithLatencyHalfLife) so it reflects recent requests;
+// the all-time average is kept alongside it, from a microsecond total that
+// only wraps after about 292,000 years of cumulative request time.
+func metricsSnapshot() gin.H {
+	total := metrics.TotalRequests.Load()
+	allTime := float64(0)
+	if total > 0 {
+		allTime = float64(metrics.totalMicros.Load()) / 1000 / float64(total)
+	}
+
+	out := gin.H{
+		"total_requests":                total,
+		"avg_response_time_all_time_ms": allTime,
+		"in_flight":                     metrics.InFlight.Load(),
+		"avg_response_time_ms":          metrics.decayedLatencyMs(),
+		"status_codes":                  metrics.StatusCodes(),
+		"error_rate_1m":                 recentErrors.rate(clk.Now()),
+		"routes":                        metrics.Routes(),
+	}
+	if cfg().dailyReset != nil {
+		out["today_requests"] = metrics.TodayRequests.Load()
+	}
+	if cfg().ginErrors {
+		out["gin_errors"] = ginErrorsSnapshot()
+	}
+	if len(cfg().dimensions) > 0 {
+		out["dimensions"] = dimensionsSnapshot()
+	}
+	if custom := sampleGauges(); custom != nil {
+		out["custom"] = custom
+	}
+	return out
 }
 
 func serverUptimeHandler(c *gin.Context) {
-	uptime := time.Since(metrics.StartTime).Seconds()
-	c.JSON(http.StatusOK, gin.H{
+	uptime := since(metrics.StartTime).Seconds()
+	respond(c, http.StatusOK, gin.H{
 		"server_uptime_seconds": uptime,
+		"breakdown":             uptimeBreakdown(uptime),
 		"server_start_time":     metrics.StartTime,
 	})
 }
 
-func networkHandler(c *gin.Context) {
-	counters, err := net.IOCounters(false)
-	if err != nil || len(counters) == 0 {
-		c.JSON(500, gin.H{"error": "cannot read network IO"})
-		return
+func loadHandler(c *gin.Context) {
+	writeCollected(c, "load", collectLoad)
+}
+
+func collectLoad() (any, error) {
+	l, err := sys.LoadAvg()
+	if err != nil {
+		return nil, err
 	}
+	return gin.H{
+		"load1":  l.Load1,
+		"load5":  l.Load5,
+		"load15": l.Load15,
+	}, nil
+}
 
-	c.JSON(200, gin.H{
+func networkHandler(c *gin.Context) {
+	writeCollected(c, "network", collectNetwork)
+}
+
+func collectNetwork() (any, error) {
+	var counters []net.IOCountersStat
+	var err error
+	if cfg().netNamespace != "" {
+		counters, err = netIOCountersInNamespace(cfg().netNamespace, false)
+	} else {
+		counters, err = sys.NetIOCounters(false)
+	}
+	if err == nil && len(counters) == 0 {
+		err = errors.New("cannot read network IO")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
 		"bytes_sent": counters[0].BytesSent,
 		"bytes_recv": counters[0].BytesRecv,
-	})
+	}, nil
 }
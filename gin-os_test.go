@@ -0,0 +1,358 @@
+package osinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// resetGlobals gives the test a fresh configuration, metrics and route
+// bookkeeping, and restores the previous values when it ends
+func resetGlobals(t testing.TB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	oldCfg, oldMetrics, oldSys, oldClk := cfg(), metrics, sys, clk
+	collectorState.mu.Lock()
+	oldStatuses := collectorState.statuses
+	collectorState.statuses = make(map[string]*collectorStatus)
+	collectorState.mu.Unlock()
+	registered.mu.Lock()
+	oldEndpoints := registered.endpoints
+	registered.endpoints = make(map[string]endpointInfo)
+	registered.mu.Unlock()
+
+	for _, c := range []*ttlCache{diskCache, infoCache, fullInfoCache} {
+		c.mu.Lock()
+		c.data, c.at = nil, time.Time{}
+		c.mu.Unlock()
+	}
+	resetCPUBaselines()
+	dimensionStats.mu.Lock()
+	oldDimensions := dimensionStats.values
+	dimensionStats.values = make(map[string]map[string]*RouteStats)
+	dimensionStats.mu.Unlock()
+
+	current.Store(defaultConfig())
+	oldRequestLog, oldGoroutineHistory := requestLog.Swap(nil), goroutineHistory
+	goroutineHistory = nil
+	metrics = &Metrics{StartTime: clk.Now()}
+	ownRoutes.mu.Lock()
+	oldOwnRoutes := ownRoutes.paths
+	ownRoutes.paths = make(map[string]bool)
+	ownRoutes.mu.Unlock()
+
+	t.Cleanup(func() {
+		resetCPUBaselines()
+		current.Store(oldCfg)
+		metrics, sys, clk = oldMetrics, oldSys, oldClk
+		requestLog.Store(oldRequestLog)
+		goroutineHistory = oldGoroutineHistory
+		ownRoutes.mu.Lock()
+		ownRoutes.paths = oldOwnRoutes
+		ownRoutes.mu.Unlock()
+		collectorState.mu.Lock()
+		collectorState.statuses = oldStatuses
+		collectorState.mu.Unlock()
+		registered.mu.Lock()
+		registered.endpoints = oldEndpoints
+		registered.mu.Unlock()
+		dimensionStats.mu.Lock()
+		dimensionStats.values = oldDimensions
+		dimensionStats.mu.Unlock()
+	})
+}
+
+// resetCPUBaselines drops every instant CPU baseline, so readings in and
+// after a test never diff CPU times from different collectors
+func resetCPUBaselines() {
+	for _, b := range cpuBaselines {
+		b.mu.Lock()
+		b.prev, b.at, b.percent, b.read = cpu.TimesStat{}, time.Time{}, 0, false
+		b.mu.Unlock()
+	}
+}
+
+// newTestEngine resets the globals and returns an engine with the osinfo
+// routes registered under prefix
+func newTestEngine(t testing.TB, prefix string, opts ...Option) *gin.Engine {
+	t.Helper()
+	resetGlobals(t)
+	r := gin.New()
+	RegisterRoutes(r, prefix, opts...)
+	return r
+}
+
+func get(r http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestRegisterRoutesNormalizesPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		health string
+	}{
+		{"os", "/os/health"},
+		{"/os", "/os/health"},
+		{"/os/", "/os/health"},
+		{"", "/health"},
+		{"/", "/health"},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Quote(tt.prefix), func(t *testing.T) {
+			r := newTestEngine(t, tt.prefix)
+			if w := get(r, tt.health); w.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d, want 200", tt.health, w.Code)
+			}
+		})
+	}
+}
+
+func TestRegisterRoutesTwice(t *testing.T) {
+	r := newTestEngine(t, "/a")
+	RegisterRoutes(r, "/b")
+	r.GET("/app", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, target := range []string{"/a/health", "/b/health"} {
+		if w := get(r, target); w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", target, w.Code)
+		}
+	}
+	if n := len(r.Handlers); n != 1 {
+		t.Errorf("engine has %d middleware, want the metrics middleware once", n)
+	}
+	get(r, "/app")
+	if n := metrics.TotalRequests.Load(); n != 1 {
+		t.Errorf("TotalRequests = %d after one app request, want 1", n)
+	}
+}
+
+var benchRoutes = []string{"/users", "/users/:id", "/orders", "/orders/:id", "/items", "/items/:id", "/search", "/login"}
+
+func BenchmarkMetricsMiddleware(b *testing.B) {
+	r := newTestEngine(b, "/os")
+	for _, route := range benchRoutes {
+		r.GET(route, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+	targets := []string{"/users", "/users/1", "/orders", "/orders/2", "/items", "/items/3", "/search", "/login"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, targets[i%len(targets)], nil))
+			i++
+		}
+	})
+}
+
+// BenchmarkMetricsRecord compares the sharded accounting with the same work
+// serialized on one mutex, as it was before sharding, under parallel load
+func BenchmarkMetricsRecord(b *testing.B) {
+	b.Run("single-lock", func(b *testing.B) {
+		resetGlobals(b)
+		var mu sync.Mutex
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				mu.Lock()
+				metrics.record(benchRoutes[i%len(benchRoutes)], http.StatusOK, time.Millisecond, 128, 1)
+				mu.Unlock()
+				i++
+			}
+		})
+	})
+	b.Run("sharded", func(b *testing.B) {
+		resetGlobals(b)
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				metrics.record(benchRoutes[i%len(benchRoutes)], http.StatusOK, time.Millisecond, 128, 1)
+				i++
+			}
+		})
+	})
+}
+
+func TestRegisterRoutesOnGroup(t *testing.T) {
+	resetGlobals(t)
+	r := gin.New()
+	admin := r.Group("/admin")
+	RegisterRoutes(admin, "/os")
+	admin.GET("/stats", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/public", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	if w := get(r, "/admin/os/health"); w.Code != http.StatusOK {
+		t.Errorf("GET /admin/os/health = %d, want 200", w.Code)
+	}
+	if w := get(r, "/os/health"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /os/health = %d, want 404 outside the group", w.Code)
+	}
+	if n := len(r.Handlers); n != 0 {
+		t.Errorf("engine has %d middleware, want the metrics middleware on the group only", n)
+	}
+
+	get(r, "/admin/stats")
+	get(r, "/public")
+	routes := metrics.Routes()
+	if n := routes["GET /admin/stats"].Count; n != 1 {
+		t.Errorf("/admin/stats counted %d times, want 1", n)
+	}
+	if _, ok := routes["GET /public"]; ok || metrics.TotalRequests.Load() != 1 {
+		t.Error("/public is outside the group but was counted")
+	}
+}
+
+func TestMaxTrackedRoutes(t *testing.T) {
+	r := newTestEngine(t, "/os", WithMaxTrackedRoutes(2))
+	for _, route := range []string{"/a", "/b", "/c", "/d"} {
+		r.GET(route, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+	for _, target := range []string{"/a", "/b", "/c", "/d", "/a", "/c"} {
+		get(r, target)
+	}
+
+	routes := metrics.Routes()
+	want := map[string]int64{"GET /a": 2, "GET /b": 1, otherRoute: 3}
+	if len(routes) != len(want) {
+		t.Errorf("tracked %d routes, want %d: %v", len(routes), len(want), routes)
+	}
+	for route, n := range want {
+		if got := routes[route].Count; got != n {
+			t.Errorf("%s counted %d times, want %d", route, got, n)
+		}
+	}
+}
+
+func TestWrongMethodOnlyAffectsOsinfoPaths(t *testing.T) {
+	resetGlobals(t)
+	r := gin.New()
+	r.HandleMethodNotAllowed = true
+	r.NoMethod(func(c *gin.Context) { c.Status(http.StatusTeapot) })
+	r.DELETE("/os/health", func(c *gin.Context) { c.Status(http.StatusAccepted) })
+	r.GET("/app", func(c *gin.Context) { c.Status(http.StatusOK) })
+	RegisterRoutes(r, "/os")
+
+	tests := []struct {
+		method, target string
+		want           int
+		allow          string
+	}{
+		{http.MethodPost, "/os/health", http.StatusMethodNotAllowed, "GET"},
+		{http.MethodPut, "/os/snapshot", http.StatusMethodNotAllowed, "POST"},
+		{http.MethodPost, "/os/static/templates/dashboard.html", http.StatusMethodNotAllowed, "GET"},
+		// registered by the application first, so it is kept
+		{http.MethodDelete, "/os/health", http.StatusAccepted, ""},
+		// the application's own NoMethod handler still applies to its routes,
+		// with the Allow header gin sets
+		{http.MethodPost, "/app", http.StatusTeapot, "GET"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.want || w.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s = %d Allow %q, want %d Allow %q",
+				tt.method, tt.target, w.Code, w.Header().Get("Allow"), tt.want, tt.allow)
+		}
+	}
+
+	// without HandleMethodNotAllowed, the application's routes keep their 404
+	resetGlobals(t)
+	r = gin.New()
+	r.GET("/app", func(c *gin.Context) { c.Status(http.StatusOK) })
+	RegisterRoutes(r, "/os")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/app", nil))
+	if w.Code != http.StatusNotFound || r.HandleMethodNotAllowed {
+		t.Errorf("POST /app = %d, HandleMethodNotAllowed %v; want 404 and false", w.Code, r.HandleMethodNotAllowed)
+	}
+}
+
+func TestRegisterRoutesWhileServing(t *testing.T) {
+	r := newTestEngine(t, "/a")
+	r.GET("/app", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	stop := make(chan struct{})
+	var wg, serving sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		serving.Add(1)
+		go func() {
+			defer wg.Done()
+			get(r, "/app")
+			serving.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				get(r, "/app")
+				get(r, "/a/health")
+				get(r, "/a/metrics")
+			}
+		}()
+	}
+
+	serving.Wait()
+	for i := 0; i < 10; i++ {
+		RegisterRoutes(gin.New(), "/b"+strconv.Itoa(i),
+			WithBasicAuth("ops", "secret"),
+			WithRequestLog(16+i),
+			WithDimensionLabels(),
+			WithFields("/mem", "usedPercent"),
+			WithDimension("tenant", func(c *gin.Context) string { return c.GetHeader("X-Tenant") }),
+			WithSecurityHeaders(map[string]string{"X-Test": strconv.Itoa(i)}))
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestSampledOutRequestsSkipBookkeeping(t *testing.T) {
+	var observed []int
+	r := newTestEngine(t, "/os",
+		WithSampleRate(1e-9),
+		WithSlowThreshold(time.Second),
+		WithBasicAuth("ops", "secret"),
+		WithRequestLog(16),
+		WithRequestObserver(func(_, _ string, status int, _ time.Duration) { observed = append(observed, status) }),
+		WithDimension("sampled", func(*gin.Context) string { return "acme" }))
+	fake := &fakeClock{now: time.Now()}
+	clk = fake
+	r.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.GET("/slow", func(c *gin.Context) {
+		fake.now = fake.now.Add(2 * time.Second)
+		c.Status(http.StatusOK)
+	})
+
+	for i := 0; i < 20; i++ {
+		get(r, "/fast")
+	}
+	if len(observed) != 0 || len(requestLog.Load().snapshot()) != 0 || len(dimensionsSnapshot()["sampled"]) != 0 {
+		t.Fatalf("sampled-out requests reached the observer %v, request log or dimensions", observed)
+	}
+
+	// server errors and slow requests are always recorded
+	get(r, "/fail")
+	get(r, "/slow")
+	if len(observed) != 2 || observed[0] != http.StatusInternalServerError || observed[1] != http.StatusOK {
+		t.Errorf("observed %v, want the failed and the slow request", observed)
+	}
+	if n := len(requestLog.Load().snapshot()); n != 2 {
+		t.Errorf("request log holds %d requests, want 2", n)
+	}
+	if n := metrics.TotalRequests.Load(); n != 2 {
+		t.Errorf("TotalRequests = %d, want 2", n)
+	}
+}
@@ -0,0 +1,53 @@
+package osinfo
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ginErrorCounts counts the errors handlers attached to c.Errors, by type,
+// under WithGinErrors
+var ginErrorCounts = struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}{counts: make(map[string]int64)}
+
+// ginErrorType names an error's gin.ErrorType for /metrics
+func ginErrorType(t gin.ErrorType) string {
+	switch {
+	case t&gin.ErrorTypeBind != 0:
+		return "bind"
+	case t&gin.ErrorTypeRender != 0:
+		return "render"
+	case t&gin.ErrorTypePublic != 0:
+		return "public"
+	case t&gin.ErrorTypePrivate != 0:
+		return "private"
+	}
+	return "other"
+}
+
+// recordGinErrors counts every error in errs, weight times
+func recordGinErrors(errs []*gin.Error, weight int64) {
+	if len(errs) == 0 {
+		return
+	}
+	ginErrorCounts.mu.Lock()
+	defer ginErrorCounts.mu.Unlock()
+
+	for _, e := range errs {
+		ginErrorCounts.counts[ginErrorType(e.Type)] += weight
+	}
+}
+
+func ginErrorsSnapshot() map[string]int64 {
+	ginErrorCounts.mu.Lock()
+	defer ginErrorCounts.mu.Unlock()
+
+	out := make(map[string]int64, len(ginErrorCounts.counts))
+	for t, n := range ginErrorCounts.counts {
+		out[t] = n
+	}
+	return out
+}
@@ -0,0 +1,287 @@
+package osinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck reports whether a dependency is healthy. Checks run with a
+// deadline and must return promptly once ctx is done; a check that ignores
+// cancellation keeps its goroutine running after /readyz has responded.
+type HealthCheck func(ctx context.Context) error
+
+// Criticality says how a failing health check affects readiness
+type Criticality int
+
+const (
+	// Critical checks make /readyz fail, and a failure skips the checks that
+	// haven't finished yet. This is the default.
+	Critical Criticality = iota
+	// NonCritical checks only mark the service degraded. They run after the
+	// critical checks have passed.
+	NonCritical
+)
+
+type namedCheck struct {
+	name        string
+	check       HealthCheck
+	criticality Criticality
+}
+
+// checkResult is the outcome of one readiness check
+type checkResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Critical   bool   `json:"critical"`
+}
+
+// healthReport is the combined outcome of the readiness checks. healthy is
+// false when a critical check failed, degraded when only non-critical ones
+// did.
+type healthReport struct {
+	results  map[string]checkResult
+	healthy  bool
+	degraded bool
+}
+
+// runHealthChecks runs the critical checks concurrently and then, if they all
+// passed, the non-critical ones. Each check is bounded by the per-check
+// timeout and all of them by the overall deadline. The first critical
+// failure cancels the critical checks still running, which are reported as
+// skipped, and the non-critical checks are skipped too. With
+// withThresholds, threshold breaches count as critical failures.
+func runHealthChecks(ctx context.Context, withThresholds bool) healthReport {
+	ctx, cancel := context.WithTimeout(ctx, cfg().healthTimeout)
+	defer cancel()
+
+	report := healthReport{
+		results: make(map[string]checkResult, len(cfg().healthChecks)),
+		healthy: true,
+	}
+
+	var critical, optional []namedCheck
+	for _, nc := range cfg().healthChecks {
+		if nc.criticality == NonCritical {
+			optional = append(optional, nc)
+		} else {
+			critical = append(critical, nc)
+		}
+	}
+
+	if withThresholds {
+		for _, r := range evaluateThresholds(cfg().thresholds, &readinessCPU) {
+			res := checkResult{Status: "ok", Critical: true}
+			if r.Breached {
+				res.Status = "fail"
+				res.Error = fmt.Sprintf("%.2f%% is at or above the %.2f%% threshold", r.Value, r.Threshold)
+				report.healthy = false
+			}
+			report.results["threshold:"+r.Metric] = res
+		}
+	}
+
+	if report.healthy {
+		for name, r := range runCheckGroup(ctx, critical, true) {
+			report.results[name] = r
+			if r.Status != "ok" && r.Status != "skipped" {
+				report.healthy = false
+			}
+		}
+	} else {
+		skip(report.results, critical, true)
+	}
+
+	if !report.healthy {
+		skip(report.results, optional, false)
+		return report
+	}
+	for name, r := range runCheckGroup(ctx, optional, false) {
+		report.results[name] = r
+		if r.Status != "ok" {
+			report.degraded = true
+		}
+	}
+	return report
+}
+
+// runCheckGroup runs checks concurrently. With failFast, the first failure
+// cancels the rest, and the checks it cut short are reported as skipped.
+func runCheckGroup(ctx context.Context, checks []namedCheck, failFast bool) map[string]checkResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type named struct {
+		name string
+		res  checkResult
+	}
+	done := make(chan named, len(checks))
+	for _, nc := range checks {
+		go func(nc namedCheck) {
+			res := runCheck(ctx, nc.check)
+			res.Critical = failFast
+			done <- named{nc.name, res}
+		}(nc)
+	}
+
+	results := make(map[string]checkResult, len(checks))
+	failed := false
+	for range checks {
+		n := <-done
+		if failed {
+			n.res = checkResult{Status: "skipped", Critical: n.res.Critical}
+		} else if n.res.Status != "ok" && failFast {
+			failed = true
+			cancel()
+		}
+		results[n.name] = n.res
+	}
+	return results
+}
+
+func skip(results map[string]checkResult, checks []namedCheck, critical bool) {
+	for _, nc := range checks {
+		results[nc.name] = checkResult{Status: "skipped", Critical: critical}
+	}
+}
+
+// runCheck runs one check under its own timeout. If the deadline passes first
+// the check is reported with a "timeout" error without waiting for it.
+func runCheck(ctx context.Context, check HealthCheck) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, cfg().checkTimeout)
+	defer cancel()
+
+	start := clk.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		r := checkResult{Status: "ok", DurationMs: since(start).Milliseconds()}
+		if err != nil {
+			r.Status = "fail"
+			r.Error = err.Error()
+		}
+		return r
+	case <-ctx.Done():
+		return checkResult{
+			Status:     "fail",
+			Error:      "timeout",
+			DurationMs: since(start).Milliseconds(),
+		}
+	}
+}
+
+var readiness struct {
+	mu       sync.Mutex
+	markedAt time.Time
+	draining bool
+}
+
+// MarkReady signals that the application has finished warming up. With
+// WithWaitForReady, /readyz reports 503 until it is called, and the
+// WithReadinessDelay grace period is counted from the call rather than from
+// startup.
+func MarkReady() {
+	readiness.mu.Lock()
+	defer readiness.mu.Unlock()
+	if readiness.markedAt.IsZero() {
+		readiness.markedAt = clk.Now()
+	}
+}
+
+// BeginShutdown makes /readyz report 503 from now on while /health keeps
+// answering 200, so load balancers stop routing new traffic to the instance
+// while it finishes in-flight requests. Call it when the shutdown signal
+// arrives, wait for the balancer to notice, then stop the server. Shutdown
+// calls it too.
+func BeginShutdown() {
+	readiness.mu.Lock()
+	defer readiness.mu.Unlock()
+	readiness.draining = true
+}
+
+func shuttingDown() bool {
+	readiness.mu.Lock()
+	defer readiness.mu.Unlock()
+	return readiness.draining
+}
+
+// startupPending reports why /readyz must not report ready yet, or "" once
+// the startup gates have passed
+func startupPending() (string, time.Duration) {
+	readiness.mu.Lock()
+	since := readiness.markedAt
+	readiness.mu.Unlock()
+
+	if since.IsZero() {
+		if cfg().waitForReady {
+			return "waiting for MarkReady", 0
+		}
+		since = metrics.StartTime
+	}
+	if left := cfg().readinessDelay - clk.Now().Sub(since); left > 0 {
+		return "warming up", left
+	}
+	return "", 0
+}
+
+// readyzHandler reports readiness: 200 when every check passes, 503 when a
+// critical check fails, while still within the startup grace period or
+// after BeginShutdown. A failed non-critical check gives 200 with
+// "degraded", or 503 with WithDegradedUnready.
+func readyzHandler(c *gin.Context) {
+	if shuttingDown() {
+		respond(c, http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+	if reason, left := startupPending(); reason != "" {
+		c.Header("Retry-After", strconv.Itoa(int(left.Seconds())+1))
+		respond(c, http.StatusServiceUnavailable, gin.H{
+			"status": "starting",
+			"reason": reason,
+		})
+		return
+	}
+	report := runHealthChecks(c.Request.Context(), true)
+
+	status, code := report.status()
+	respond(c, code, gin.H{
+		"status":   strings.ToLower(status),
+		"degraded": report.degraded,
+		"checks":   report.results,
+	})
+}
+
+// status is the overall state, OK, DEGRADED or FAIL, with its HTTP status
+func (r healthReport) status() (string, int) {
+	switch {
+	case !r.healthy:
+		return "FAIL", http.StatusServiceUnavailable
+	case r.degraded && cfg().degradedUnready:
+		return "DEGRADED", http.StatusServiceUnavailable
+	case r.degraded:
+		return "DEGRADED", http.StatusOK
+	}
+	return "OK", http.StatusOK
+}
+
+// statusHandler is a minimal probe for external uptime monitors: plain text
+// "OK", "DEGRADED" or "FAIL" with the same status codes as /readyz. It runs
+// the registered health checks but skips the threshold collectors unless
+// ?thresholds=true.
+func statusHandler(c *gin.Context) {
+	withThresholds, _ := strconv.ParseBool(c.Query("thresholds"))
+	setCacheControl(c)
+	status, code := runHealthChecks(c.Request.Context(), withThresholds).status()
+	c.String(code, status)
+}
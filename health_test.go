@@ -0,0 +1,59 @@
+package osinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSlowHealthCheckTimesOut(t *testing.T) {
+	// stuck ignores its context, like a check blocked on a call without a
+	// deadline; it is released when the test ends
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	stuck := func(context.Context) error {
+		<-release
+		return nil
+	}
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name              string
+		check             HealthCheck
+		perCheck, overall time.Duration
+	}{
+		{"per-check deadline", slow, 50 * time.Millisecond, time.Second},
+		{"overall deadline", slow, time.Second, 50 * time.Millisecond},
+		{"check ignoring its context", stuck, 50 * time.Millisecond, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestEngine(t, "/os",
+				WithHealthCheck("slow", tt.check),
+				WithHealthCheckTimeout(tt.perCheck, tt.overall))
+
+			start := time.Now()
+			w := get(r, "/os/readyz")
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("/readyz took %s, want it bounded by the deadline", elapsed)
+			}
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("GET /os/readyz = %d, want 503", w.Code)
+			}
+			var body struct {
+				Checks map[string]checkResult `json:"checks"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if res := body.Checks["slow"]; res.Status != "fail" || res.Error != "timeout" {
+				t.Errorf("slow check = %+v, want fail with a timeout error", res)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package osinfo
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultIdentityEnv maps the /identity fields to the environment variables
+// conventionally filled in by the Kubernetes downward API
+var defaultIdentityEnv = map[string]string{
+	"pod_name":      "POD_NAME",
+	"pod_namespace": "POD_NAMESPACE",
+	"pod_ip":        "POD_IP",
+	"node_name":     "NODE_NAME",
+}
+
+// serviceAccountDir is where Kubernetes mounts the pod's service account
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// identityHandler reports which pod and node this replica runs as. Fields
+// whose variable is unset are null and listed under "unset". The service
+// account token is never returned, only whether it is mounted.
+func identityHandler(c *gin.Context) {
+	out := gin.H{}
+	unset := []string{}
+	for field, env := range cfg().identityEnv {
+		if v, ok := os.LookupEnv(env); ok && v != "" {
+			out[field] = v
+			continue
+		}
+		out[field] = nil
+		unset = append(unset, field)
+	}
+	sort.Strings(unset)
+	out["unset"] = unset
+
+	out["service_account"] = nil
+	if _, err := os.Stat(serviceAccountDir); err == nil {
+		sa := gin.H{"token_mounted": false}
+		if b, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+			sa["namespace"] = strings.TrimSpace(string(b))
+		}
+		if _, err := os.Stat(filepath.Join(serviceAccountDir, "token")); err == nil {
+			sa["token_mounted"] = true
+		}
+		out["service_account"] = sa
+	}
+	respond(c, http.StatusOK, out)
+}
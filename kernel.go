@@ -0,0 +1,27 @@
+package osinfo
+
+import "github.com/gin-gonic/gin"
+
+// kernelHandler reports virtualization details on every platform, plus
+// kernel tuning settings where the platform exposes them
+func kernelHandler(c *gin.Context) {
+	data, err := runCollector("kernel", collectKernel)
+	writeResult(c, data, err)
+}
+
+func collectKernel() (any, error) {
+	system, role, err := sys.Virtualization()
+	if err != nil {
+		return nil, err
+	}
+	out := gin.H{
+		"virtualization": gin.H{
+			"system": system,
+			"role":   role,
+		},
+	}
+	if settings := kernelSettings(); len(settings) > 0 {
+		out["settings"] = settings
+	}
+	return out, nil
+}
@@ -0,0 +1,45 @@
+//go:build linux
+
+package osinfo
+
+import (
+	"os"
+	"strings"
+)
+
+// kernelSettingFiles are the sysctls and sysfs knobs reported by /kernel
+var kernelSettingFiles = map[string]string{
+	"transparent_hugepage":        "/sys/kernel/mm/transparent_hugepage/enabled",
+	"transparent_hugepage_defrag": "/sys/kernel/mm/transparent_hugepage/defrag",
+	"vm.swappiness":               "/proc/sys/vm/swappiness",
+	"vm.overcommit_memory":        "/proc/sys/vm/overcommit_memory",
+	"vm.dirty_ratio":              "/proc/sys/vm/dirty_ratio",
+	"kernel.pid_max":              "/proc/sys/kernel/pid_max",
+	"fs.file-max":                 "/proc/sys/fs/file-max",
+	"net.core.somaxconn":          "/proc/sys/net/core/somaxconn",
+}
+
+// kernelSettings reads the settings that are available; missing or
+// unreadable files are left out
+func kernelSettings() map[string]string {
+	out := make(map[string]string, len(kernelSettingFiles))
+	for name, path := range kernelSettingFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		out[name] = selectedOption(strings.TrimSpace(string(b)))
+	}
+	return out
+}
+
+// selectedOption extracts the active choice from sysfs values such as
+// "always [madvise] never", returning other values unchanged
+func selectedOption(v string) string {
+	start := strings.IndexByte(v, '[')
+	end := strings.IndexByte(v, ']')
+	if start < 0 || end < start {
+		return v
+	}
+	return v[start+1 : end]
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package osinfo
+
+func kernelSettings() map[string]string {
+	return nil
+}
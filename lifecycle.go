@@ -0,0 +1,74 @@
+package osinfo
+
+import (
+	"context"
+	"sync"
+)
+
+// background tracks the goroutines started by options such as
+// WithAlertWebhook so Shutdown can stop them
+var background = struct {
+	mu      sync.Mutex
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	running map[string]bool
+}{running: make(map[string]bool)}
+
+// startBackground runs fn in a goroutine until Shutdown closes stop. Only one
+// goroutine per name runs at a time, so registering routes twice is harmless.
+func startBackground(name string, fn func(stop <-chan struct{})) {
+	background.mu.Lock()
+	defer background.mu.Unlock()
+
+	if background.running[name] {
+		return
+	}
+	if background.stop == nil {
+		background.stop = make(chan struct{})
+	}
+	background.running[name] = true
+	background.wg.Add(1)
+	go func(stop <-chan struct{}) {
+		defer background.wg.Done()
+		fn(stop)
+	}(background.stop)
+}
+
+// streams is closed by Shutdown to end the long-lived streaming responses
+// (/dashboard-stream, /requests/stream), which would otherwise hold
+// http.Server.Shutdown until its deadline
+var streams = struct {
+	once sync.Once
+	done chan struct{}
+}{done: make(chan struct{})}
+
+func streamsDone() <-chan struct{} {
+	return streams.done
+}
+
+// Shutdown stops the background goroutines started by RegisterRoutes options
+// and waits for them to exit, or for ctx to be done. It also calls
+// BeginShutdown, so /readyz fails from then on, and ends the open streams.
+func Shutdown(ctx context.Context) error {
+	BeginShutdown()
+	streams.once.Do(func() { close(streams.done) })
+	background.mu.Lock()
+	if background.stop != nil {
+		close(background.stop)
+		background.stop = nil
+	}
+	background.running = make(map[string]bool)
+	background.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		background.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,18 @@
+package osinfo
+
+import "github.com/gin-gonic/gin"
+
+// QuietLogging returns gin's access logger configured to skip the osinfo
+// routes, so frequent health and metrics scrapes don't flood the logs. Use it
+// with gin.New() in place of the logger installed by gin.Default():
+//
+//	r := gin.New()
+//	r.Use(osinfo.QuietLogging(), gin.Recovery())
+//	osinfo.RegisterRoutes(r, "/os")
+func QuietLogging() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Skip: func(c *gin.Context) bool {
+			return isOwnRoute(c.FullPath())
+		},
+	})
+}
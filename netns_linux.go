@@ -0,0 +1,65 @@
+//go:build linux
+
+package osinfo
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"golang.org/x/sys/unix"
+)
+
+// netIOCountersInNamespace reads network counters from inside the network
+// namespace at nsPath (e.g. /var/run/netns/foo or /proc/<pid>/ns/net).
+//
+// setns only affects the calling OS thread, so the work runs on a fresh
+// goroutine locked to its thread. If the original namespace cannot be
+// restored the thread is left locked and is discarded when the goroutine
+// exits, so no other goroutine ever runs inside the foreign namespace.
+func netIOCountersInNamespace(nsPath string, pernic bool) ([]net.IOCountersStat, error) {
+	type result struct {
+		counters []net.IOCountersStat
+		err      error
+	}
+	ch := make(chan result, 1)
+
+	go func() {
+		runtime.LockOSThread()
+
+		orig, err := os.Open("/proc/thread-self/ns/net")
+		if err != nil {
+			runtime.UnlockOSThread()
+			ch <- result{err: err}
+			return
+		}
+		defer orig.Close()
+
+		target, err := os.Open(nsPath)
+		if err != nil {
+			runtime.UnlockOSThread()
+			ch <- result{err: err}
+			return
+		}
+		defer target.Close()
+
+		if err := unix.Setns(int(target.Fd()), unix.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			ch <- result{err: fmt.Errorf("entering network namespace %s: %w", nsPath, err)}
+			return
+		}
+
+		// /proc/self/net follows the thread group leader, so read the
+		// thread's own view
+		counters, err := net.IOCountersByFile(pernic, "/proc/thread-self/net/dev")
+
+		if unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET) == nil {
+			runtime.UnlockOSThread()
+		}
+		ch <- result{counters: counters, err: err}
+	}()
+
+	r := <-ch
+	return r.counters, r.err
+}
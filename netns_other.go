@@ -0,0 +1,13 @@
+//go:build !linux
+
+package osinfo
+
+import (
+	"errors"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+func netIOCountersInNamespace(nsPath string, pernic bool) ([]net.IOCountersStat, error) {
+	return nil, errors.New("network namespaces are only supported on Linux")
+}
@@ -0,0 +1,91 @@
+package osinfo
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// netIOPrev is the sample the next /network/interfaces call computes its
+// rates against
+var netIOPrev struct {
+	mu       sync.Mutex
+	at       time.Time
+	counters map[string]net.IOCountersStat
+}
+
+// networkInterfacesHandler reports cumulative counters per interface along
+// with receive and transmit throughput and packet rates since the previous
+// call. Like /diskio, the first call reports zero rates with
+// no_prior_sample.
+func networkInterfacesHandler(c *gin.Context) {
+	writeCollected(c, "network", collectNetworkInterfaces)
+}
+
+func collectNetworkInterfaces() (any, error) {
+	var list []net.IOCountersStat
+	var err error
+	if cfg().netNamespace != "" {
+		list, err = netIOCountersInNamespace(cfg().netNamespace, true)
+	} else {
+		list, err = sys.NetIOCounters(true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := clk.Now()
+	counters := make(map[string]net.IOCountersStat, len(list))
+	for _, s := range list {
+		counters[s.Name] = s
+	}
+
+	netIOPrev.mu.Lock()
+	prev, prevAt := netIOPrev.counters, netIOPrev.at
+	netIOPrev.counters, netIOPrev.at = counters, now
+	netIOPrev.mu.Unlock()
+
+	elapsed := now.Sub(prevAt).Seconds()
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	interfaces := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		cur := counters[name]
+		var rxBps, txBps, rxPps, txPps float64
+		if p, ok := prev[name]; ok && elapsed > 0 {
+			rxBps = counterRate(p.BytesRecv, cur.BytesRecv, elapsed)
+			txBps = counterRate(p.BytesSent, cur.BytesSent, elapsed)
+			rxPps = counterRate(p.PacketsRecv, cur.PacketsRecv, elapsed)
+			txPps = counterRate(p.PacketsSent, cur.PacketsSent, elapsed)
+		}
+		interfaces = append(interfaces, gin.H{
+			"name":               name,
+			"bytes_recv":         cur.BytesRecv,
+			"bytes_sent":         cur.BytesSent,
+			"packets_recv":       cur.PacketsRecv,
+			"packets_sent":       cur.PacketsSent,
+			"errin":              cur.Errin,
+			"errout":             cur.Errout,
+			"dropin":             cur.Dropin,
+			"dropout":            cur.Dropout,
+			"rx_bytes_per_sec":   rxBps,
+			"tx_bytes_per_sec":   txBps,
+			"rx_packets_per_sec": rxPps,
+			"tx_packets_per_sec": txPps,
+		})
+	}
+
+	out := gin.H{"interfaces": interfaces}
+	if prev == nil {
+		out["no_prior_sample"] = true
+	} else {
+		out["interval_seconds"] = elapsed
+	}
+	return out, nil
+}
@@ -0,0 +1,915 @@
+package osinfo
+
+import (
+	"log"
+	"log/slog"
+	"maps"
+	"math"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Option configures the behaviour of RegisterRoutes
+type Option func(*config)
+
+type config struct {
+	prefix          string
+	disabled        map[string]bool
+	customEndpoints []customEndpoint
+	authUser        string
+	authPass        string
+	tieredEnv       bool
+	profiling       bool
+
+	latencyBuckets  []float64
+	metricNamespace string
+	openMetrics     bool
+	observers       []RequestObserver
+	constLabels     map[string]string
+	expvar          bool
+
+	securityHeaders  map[string]string
+	systemInMetrics  bool
+	maxTrackedRoutes int
+	dimensions       []dimension
+	dimensionLabels  bool
+	ginErrors        bool
+	sampleEvery      int64
+	slowThreshold    time.Duration
+	dailyReset       *time.Location
+	latencyHalfLife  time.Duration
+	logger           *slog.Logger
+	processLimit     int
+	rootMount        string
+	dashboardPath    string
+	embeddable       bool
+	frameAncestors   []string
+	dashboardLayout  []string
+	dashboardRefresh time.Duration
+	maxStreamClients int
+	percentDecimals  int
+
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	retryAttempts    int
+	retryBackoff     time.Duration
+
+	cacheTTL             time.Duration
+	cacheTTLSet          bool
+	cacheTTLs            map[string]time.Duration
+	excludeFstypes       map[string]bool
+	physicalDisksOnly    bool
+	excludeNetworkFS     bool
+	diskUsageTimeout     time.Duration
+	fields               map[string][]string
+	maxPartitions        int
+	aggregateConcurrency int
+
+	cpuSamples      int
+	cpuSampleBudget time.Duration
+
+	thresholds      Thresholds
+	healthChecks    []namedCheck
+	degradedUnready bool
+	checkTimeout    time.Duration
+	healthTimeout   time.Duration
+	alertWebhook    string
+	alertInterval   time.Duration
+	alertDebounce   int
+	statsdAddr      string
+	statsdPrefix    string
+
+	readinessDelay  time.Duration
+	waitForReady    bool
+	emptyHealthBody bool
+	healthDetails   bool
+
+	goroutineInterval time.Duration
+	goroutineSamples  int
+
+	requestLogSize int
+
+	netNamespace string
+	identityEnv  map[string]string
+	envelope     bool
+	cacheControl string
+}
+
+func defaultConfig() *config {
+	return &config{
+		disabled: make(map[string]bool),
+
+		latencyBuckets:   prometheus.DefBuckets,
+		securityHeaders:  defaultSecurityHeaders(),
+		processLimit:     500,
+		maxTrackedRoutes: 1000,
+		latencyHalfLife:  time.Minute,
+		rootMount:        defaultRootMount(),
+		dashboardPath:    "/dashboard",
+		dashboardRefresh: 2 * time.Second,
+		maxStreamClients: 64,
+		percentDecimals:  2,
+
+		breakerThreshold: 5,
+		breakerCooldown:  30 * time.Second,
+		retryAttempts:    2,
+		retryBackoff:     50 * time.Millisecond,
+
+		cacheTTLs:            make(map[string]time.Duration),
+		diskUsageTimeout:     2 * time.Second,
+		aggregateConcurrency: runtime.NumCPU(),
+
+		cpuSamples:      1,
+		cpuSampleBudget: 5 * time.Second,
+
+		checkTimeout:  2 * time.Second,
+		healthTimeout: 5 * time.Second,
+		alertDebounce: 3,
+
+		cacheControl: "no-store",
+		identityEnv:  maps.Clone(defaultIdentityEnv),
+		logger:       slog.Default(),
+	}
+}
+
+// current holds the configuration in effect. It is only replaced, never
+// modified, so handlers can read it while RegisterRoutes applies options.
+var current atomic.Pointer[config]
+
+func init() {
+	current.Store(defaultConfig())
+}
+
+// cfg returns the configuration in effect
+func cfg() *config {
+	return current.Load()
+}
+
+// clone copies c deeply enough that options applied to the copy don't
+// modify the maps and slices c shares with handlers reading it
+func (c *config) clone() *config {
+	out := *c
+	out.disabled = maps.Clone(c.disabled)
+	out.customEndpoints = slices.Clone(c.customEndpoints)
+	out.latencyBuckets = slices.Clone(c.latencyBuckets)
+	out.observers = slices.Clone(c.observers)
+	out.constLabels = maps.Clone(c.constLabels)
+	out.securityHeaders = maps.Clone(c.securityHeaders)
+	out.dimensions = slices.Clone(c.dimensions)
+	out.frameAncestors = slices.Clone(c.frameAncestors)
+	out.dashboardLayout = slices.Clone(c.dashboardLayout)
+	out.cacheTTLs = maps.Clone(c.cacheTTLs)
+	out.excludeFstypes = maps.Clone(c.excludeFstypes)
+	out.fields = maps.Clone(c.fields)
+	out.healthChecks = slices.Clone(c.healthChecks)
+	out.identityEnv = maps.Clone(c.identityEnv)
+	return &out
+}
+
+func defaultRootMount() string {
+	if runtime.GOOS == "windows" {
+		return `C:\`
+	}
+	return "/"
+}
+
+// WithoutEndpoints skips registering the given endpoints, given as paths
+// relative to the prefix such as "/env" or "/processes".
+func WithoutEndpoints(paths ...string) Option {
+	return func(c *config) {
+		for _, p := range paths {
+			if !strings.HasPrefix(p, "/") {
+				p = "/" + p
+			}
+			c.disabled[p] = true
+		}
+	}
+}
+
+// WithFields trims the JSON returned by endpoint, a path relative to the
+// prefix such as "/mem", to the named top-level fields; for endpoints that
+// return a list, such as /disk, each element is trimmed, also inside the
+// partitions wrapper of WithMaxPartitions. Degraded and error bodies are
+// returned whole, and cached_at and age_ms are always kept. Use it to shrink
+// payloads or hide values such as exact memory totals. The matching sections
+// of /batch, /dashboard-data, /dashboard-stream and the dashboard export are
+// trimmed the same way; /snapshot and /diff, which record their own named
+// values, are not. Calling it again for the same endpoint replaces its
+// fields.
+func WithFields(endpoint string, fields ...string) Option {
+	return func(c *config) {
+		if !strings.HasPrefix(endpoint, "/") {
+			endpoint = "/" + endpoint
+		}
+		if c.fields == nil {
+			c.fields = make(map[string][]string)
+		}
+		c.fields[endpoint] = append([]string{}, fields...)
+	}
+}
+
+// WithLatencyBuckets sets the upper bounds, in seconds, of the request
+// latency histogram. Buckets must be positive and strictly increasing;
+// otherwise prometheus.DefBuckets is used.
+func WithLatencyBuckets(buckets []float64) Option {
+	return func(c *config) {
+		if !validBuckets(buckets) {
+			log.Printf("osinfo: invalid latency buckets %v, using defaults", buckets)
+			c.latencyBuckets = prometheus.DefBuckets
+			return
+		}
+		c.latencyBuckets = append([]float64(nil), buckets...)
+	}
+}
+
+func validBuckets(buckets []float64) bool {
+	if len(buckets) == 0 || buckets[0] <= 0 {
+		return false
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+// WithExpvar publishes the request metrics as expvar variables and serves
+// the standard /debug/vars handler under the group, behind WithBasicAuth
+// when it is set.
+func WithExpvar() Option {
+	return func(c *config) {
+		c.expvar = true
+	}
+}
+
+// WithSecurityHeaders overrides the security headers sent with the dashboard
+// and static assets. Entries replace the defaults (Content-Security-Policy,
+// X-Content-Type-Options, X-Frame-Options); an empty value removes a header.
+func WithSecurityHeaders(headers map[string]string) Option {
+	return func(c *config) {
+		for k, v := range headers {
+			if v == "" {
+				delete(c.securityHeaders, k)
+				continue
+			}
+			c.securityHeaders[k] = v
+		}
+	}
+}
+
+// WithSystemMetrics makes /metrics include the current system gauges under
+// "system" by default. Callers can still override it with ?system=false.
+func WithSystemMetrics() Option {
+	return func(c *config) {
+		c.systemInMetrics = true
+	}
+}
+
+// WithProcessLimit caps the number of entries /processes returns (default 500).
+// The ?limit query parameter can only lower it.
+func WithProcessLimit(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.processLimit = n
+		}
+	}
+}
+
+// WithRootMount sets the mountpoint treated as the primary disk wherever a
+// single representative disk usage is reported. Defaults to "/" (`C:\` on
+// Windows).
+func WithRootMount(path string) Option {
+	return func(c *config) {
+		if path != "" {
+			c.rootMount = path
+		}
+	}
+}
+
+// WithDashboardPath sets where the dashboard is served, relative to the
+// prefix (default "/dashboard"). Static assets stay under /static.
+func WithDashboardPath(path string) Option {
+	return func(c *config) {
+		path = strings.TrimSuffix(path, "/")
+		if path == "" {
+			return
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		c.dashboardPath = path
+	}
+}
+
+// WithPercentPrecision sets how many decimal places percentage fields are
+// rounded to (default 2). Requests with ?raw=true get full precision.
+func WithPercentPrecision(decimals int) Option {
+	return func(c *config) {
+		if decimals >= 0 {
+			c.percentDecimals = decimals
+		}
+	}
+}
+
+// WithCircuitBreaker makes a collector short-circuit for cooldown after
+// threshold consecutive failures (default 5 failures, 30s). A threshold of 0
+// disables the breaker.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *config) {
+		c.breakerThreshold = threshold
+		c.breakerCooldown = cooldown
+	}
+}
+
+// WithCacheTTL sets how long the cached collectors, disk and info, reuse a
+// result (by default disk 5s and info not cached). Responses served from
+// the cache carry an Age header and, for JSON objects, cached_at and age_ms.
+// A ttl of 0 disables caching. /processes is never cached.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.cacheTTL = ttl
+		c.cacheTTLSet = true
+	}
+}
+
+// WithCollectorCacheTTL overrides the cache TTL for one collector, "disk" or
+// "info", taking precedence over WithCacheTTL.
+func WithCollectorCacheTTL(name string, ttl time.Duration) Option {
+	return func(c *config) {
+		c.cacheTTLs[name] = ttl
+	}
+}
+
+// WithDiskCacheTTL sets how long /disk results are reused before partitions
+// are enumerated again (default 5s). A ttl of 0 disables caching. It is
+// shorthand for WithCollectorCacheTTL("disk", ttl).
+func WithDiskCacheTTL(ttl time.Duration) Option {
+	return WithCollectorCacheTTL("disk", ttl)
+}
+
+// WithThresholds sets the usage percentages above which the host is
+// considered unhealthy.
+func WithThresholds(t Thresholds) Option {
+	return func(c *config) {
+		c.thresholds = t
+	}
+}
+
+// WithAlertWebhook evaluates the thresholds every checkInterval in the
+// background and POSTs a JSON alert to url whenever a metric starts or stops
+// breaching its threshold, after WithAlertDebounce consecutive checks agree.
+// An alert the webhook doesn't accept with a 2xx is sent again on the next
+// check. The goroutine is stopped by Shutdown.
+func WithAlertWebhook(url string, checkInterval time.Duration) Option {
+	return func(c *config) {
+		c.alertWebhook = url
+		c.alertInterval = checkInterval
+	}
+}
+
+// WithAlertDebounce sets how many consecutive checks must see a metric
+// breaching (or back under) its threshold before WithAlertWebhook fires (or
+// resolves), default 3, so a value hovering around the threshold doesn't
+// alert on every check. 1 alerts on the first check.
+func WithAlertDebounce(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.alertDebounce = n
+		}
+	}
+}
+
+// WithNetNamespace makes /network read its counters from inside the network
+// namespace at path, such as /var/run/netns/<name> or /proc/<pid>/ns/net.
+// Linux only; the read happens on a dedicated, locked OS thread and requires
+// CAP_SYS_ADMIN.
+func WithNetNamespace(path string) Option {
+	return func(c *config) {
+		c.netNamespace = path
+	}
+}
+
+// WithEnvelope wraps every JSON response as {"data": ..., "meta": {...}}, with
+// the timestamp, endpoint and hostname in meta. The streamed /processes
+// response and /dashboard-data, which the dashboard reads as is, are not
+// wrapped.
+func WithEnvelope() Option {
+	return func(c *config) {
+		c.envelope = true
+	}
+}
+
+// WithRetry sets how many times a failing collector is attempted per request
+// (default 2) and the initial backoff between attempts, which doubles each
+// time (default 50ms). Permission and unsupported errors are not retried.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(c *config) {
+		if attempts > 0 {
+			c.retryAttempts = attempts
+		}
+		c.retryBackoff = backoff
+	}
+}
+
+// WithCPUSampling sets how many 500ms CPU samples /cpu averages by default
+// (default 1) and the total time a request may spend sampling (default 5s),
+// which also caps ?samples=N.
+func WithCPUSampling(samples int, budget time.Duration) Option {
+	return func(c *config) {
+		if samples > 0 {
+			c.cpuSamples = samples
+		}
+		if budget > 0 {
+			c.cpuSampleBudget = budget
+		}
+	}
+}
+
+var metricNamespaceRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// WithMetricNamespace prefixes the custom Prometheus metric names with ns_,
+// e.g. myapp_osinfo_request_duration_seconds. ns must be a valid Prometheus
+// name without colons; invalid values are ignored.
+func WithMetricNamespace(ns string) Option {
+	return func(c *config) {
+		if !metricNamespaceRE.MatchString(ns) {
+			log.Printf("osinfo: invalid metric namespace %q, ignoring", ns)
+			return
+		}
+		c.metricNamespace = ns
+	}
+}
+
+// WithConstantLabels attaches labels such as {"region": "eu-west-1"} to
+// every osinfo Prometheus metric and adds them to the envelope meta of
+// the JSON responses (see WithEnvelope). Labels with invalid names are
+// ignored.
+func WithConstantLabels(labels map[string]string) Option {
+	return func(c *config) {
+		for k, v := range labels {
+			if !metricNamespaceRE.MatchString(k) || strings.HasPrefix(k, "__") {
+				log.Printf("osinfo: invalid label name %q, ignoring", k)
+				continue
+			}
+			if c.constLabels == nil {
+				c.constLabels = make(map[string]string)
+			}
+			c.constLabels[k] = v
+		}
+	}
+}
+
+// RequestObserver is told about every request the metrics middleware
+// records: its method, matched route, status and duration. Observers run on
+// the request path and must be fast. Under WithSampleRate they see only the
+// recorded requests, without the scaling.
+type RequestObserver func(method, route string, status int, elapsed time.Duration)
+
+// WithRequestObserver feeds the request metrics to fn as well, e.g. to
+// export them to another metrics system (see the osinfootel package). A nil
+// fn is ignored.
+func WithRequestObserver(fn RequestObserver) Option {
+	return func(c *config) {
+		if fn != nil {
+			c.observers = append(c.observers, fn)
+		}
+	}
+}
+
+// WithOpenMetrics lets /gui-metrics answer in the OpenMetrics format
+// (application/openmetrics-text, ending in "# EOF") when the scraper's Accept
+// header asks for it. Other clients still get the classic text format.
+func WithOpenMetrics() Option {
+	return func(c *config) {
+		c.openMetrics = true
+	}
+}
+
+// WithHealthCheck adds a named check to /readyz. See HealthCheck for how
+// checks must treat their context. Checks are Critical unless NonCritical is
+// passed, in which case a failure only marks the service degraded.
+func WithHealthCheck(name string, check HealthCheck, criticality ...Criticality) Option {
+	return func(c *config) {
+		nc := namedCheck{name: name, check: check}
+		if len(criticality) > 0 {
+			nc.criticality = criticality[0]
+		}
+		c.healthChecks = append(c.healthChecks, nc)
+	}
+}
+
+// WithDegradedUnready makes /readyz and /status answer 503 rather than 200
+// when only non-critical checks fail.
+func WithDegradedUnready() Option {
+	return func(c *config) {
+		c.degradedUnready = true
+	}
+}
+
+// WithHealthCheckTimeout sets the deadline for each check (default 2s) and
+// for the whole /readyz response (default 5s). Checks still running at their
+// deadline are reported as failed with a "timeout" error.
+func WithHealthCheckTimeout(perCheck, overall time.Duration) Option {
+	return func(c *config) {
+		if perCheck > 0 {
+			c.checkTimeout = perCheck
+		}
+		if overall > 0 {
+			c.healthTimeout = overall
+		}
+	}
+}
+
+// WithAggregateConcurrency bounds how many collectors the aggregate endpoints
+// such as /batch run at once (default runtime.NumCPU()).
+func WithAggregateConcurrency(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.aggregateConcurrency = n
+		}
+	}
+}
+
+// WithMaxTrackedRoutes caps how many routes get their own entry in the
+// per-route metrics (default 1000). Further routes are counted together
+// under "<other>", so high route cardinality can't grow memory unbounded.
+func WithMaxTrackedRoutes(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxTrackedRoutes = n
+		}
+	}
+}
+
+// WithDimension breaks the request metrics down by the value extract returns
+// for each request, e.g. a tenant ID an earlier middleware stored in the
+// context. It runs after the handler, so values set by the handler count too.
+// /metrics lists count, latency and bytes per value under dimensions.name;
+// requests with an empty value are left out, and values past
+// WithMaxTrackedRoutes are grouped under "<other>". A repeated name replaces
+// the earlier extractor.
+func WithDimension(name string, extract func(*gin.Context) string) Option {
+	return func(c *config) {
+		if name == "" || extract == nil {
+			return
+		}
+		for i, d := range c.dimensions {
+			if d.name == name {
+				c.dimensions[i].extract = extract
+				return
+			}
+		}
+		c.dimensions = append(c.dimensions, dimension{name: name, extract: extract})
+	}
+}
+
+// WithDimensionLabels exports the WithDimension breakdowns to Prometheus as
+// osinfo_dimension_request_duration_seconds, labelled with the dimension
+// name and value.
+func WithDimensionLabels() Option {
+	return func(c *config) {
+		c.dimensionLabels = true
+	}
+}
+
+// WithGinErrors surfaces the errors handlers add with c.Error: /metrics
+// counts them by type under gin_errors (bind, render, public, private or
+// other), and WithRequestLog entries carry the last one's message in error.
+// Messages may contain application details.
+func WithGinErrors() Option {
+	return func(c *config) {
+		c.ginErrors = true
+	}
+}
+
+// WithCustomEndpoint registers an application-defined GET handler on the
+// osinfo group at path (relative to the prefix). It is listed in /routes with
+// description and, like the built-in endpoints, left out of the request
+// metrics.
+func WithCustomEndpoint(path, description string, handler gin.HandlerFunc) Option {
+	return func(c *config) {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		c.customEndpoints = append(c.customEndpoints, customEndpoint{
+			path:        path,
+			description: description,
+			handler:     handler,
+		})
+	}
+}
+
+// WithProcFile registers a GET endpoint at name (relative to the prefix) that
+// reads path, typically under /proc or /sys, and responds with whatever
+// parser returns. A missing file is a 404 and a parser error a 500. The
+// endpoint answers 501 on systems other than Linux.
+func WithProcFile(name, path string, parser func([]byte) (any, error)) Option {
+	return WithCustomEndpoint(name, "contents of "+path, procFileHandler(path, parser))
+}
+
+// WithBasicAuth requires HTTP basic auth with the given credentials on the
+// sensitive endpoints: /env and the profiling endpoints.
+func WithBasicAuth(username, password string) Option {
+	return func(c *config) {
+		c.authUser = username
+		c.authPass = password
+	}
+}
+
+// WithTieredEnv opens /env to unauthenticated callers, who see every
+// variable name with its value redacted; requests carrying the WithBasicAuth
+// credentials get the real values. Without WithBasicAuth nobody is trusted,
+// so all values stay redacted. ?prefix= filtering applies to both views.
+func WithTieredEnv() Option {
+	return func(c *config) {
+		c.tieredEnv = true
+	}
+}
+
+// WithProfiling registers /prof/heap, which downloads a heap profile, and
+// /prof/goroutine, which dumps goroutine stacks. They are only registered
+// together with WithBasicAuth.
+func WithProfiling() Option {
+	return func(c *config) {
+		c.profiling = true
+	}
+}
+
+// WithCacheControl sets the Cache-Control header sent with the JSON
+// endpoints (default "no-store", which also sends Pragma: no-cache). A short
+// policy such as "max-age=2" lets caches absorb scrape bursts. The dashboard
+// and static assets keep their own cacheable headers.
+func WithCacheControl(policy string) Option {
+	return func(c *config) {
+		if policy != "" {
+			c.cacheControl = policy
+		}
+	}
+}
+
+// WithExcludeFstypes leaves partitions with the given filesystem types, such
+// as "squashfs" or "overlay", out of /disk and the disk gauges.
+func WithExcludeFstypes(types ...string) Option {
+	return func(c *config) {
+		if c.excludeFstypes == nil {
+			c.excludeFstypes = make(map[string]bool)
+		}
+		for _, t := range types {
+			c.excludeFstypes[t] = true
+		}
+	}
+}
+
+// WithPhysicalDisksOnly limits /disk and the disk gauges to block-backed
+// filesystems: a device under /dev/ and a filesystem type such as ext4, xfs,
+// btrfs or ntfs. Pseudo filesystems like tmpfs, devtmpfs and overlay are
+// left out.
+func WithPhysicalDisksOnly() Option {
+	return func(c *config) {
+		c.physicalDisksOnly = true
+	}
+}
+
+// WithNetworkFilesystems controls whether network filesystems (nfs, cifs,
+// FUSE mounts and the like) are included in /disk and the disk gauges. They
+// are included by default.
+func WithNetworkFilesystems(include bool) Option {
+	return func(c *config) {
+		c.excludeNetworkFS = !include
+	}
+}
+
+// WithDiskUsageTimeout bounds how long reading one mount's usage may take
+// (default 2s), so a hung network mount can't stall /disk. Mounts that time
+// out are listed with an "error" field instead of usage figures. A timeout
+// of 0 waits indefinitely.
+func WithDiskUsageTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.diskUsageTimeout = d
+	}
+}
+
+// WithMaxPartitions caps how many partitions /disk reports, counted after
+// WithExcludeFstypes is applied. With a cap set, /disk returns
+// {"partitions": [...], "total": n, "truncated": bool} instead of a bare list.
+func WithMaxPartitions(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxPartitions = n
+		}
+	}
+}
+
+// WithEmbeddable allows the dashboard to be framed by the given origins, e.g.
+// "https://portal.example.com", by setting the CSP frame-ancestors directive
+// and dropping X-Frame-Options. With no origins only same-origin framing is
+// allowed. The directive is merged into whichever CSP is in effect, including
+// one set with WithSecurityHeaders before or after this option. The
+// dashboard also switches to a compact layout without the title bar.
+func WithEmbeddable(allowedAncestors ...string) Option {
+	return func(c *config) {
+		if len(allowedAncestors) == 0 {
+			allowedAncestors = []string{"'self'"}
+		}
+		c.embeddable = true
+		c.frameAncestors = allowedAncestors
+	}
+}
+
+// WithStatsD sends the request counts, latency, status codes and system
+// gauges to the StatsD (or DogStatsD) server at addr over UDP every 10s,
+// with names prefixed by prefix. The goroutine is stopped by Shutdown.
+func WithStatsD(addr, prefix string) Option {
+	return func(c *config) {
+		c.statsdAddr = addr
+		c.statsdPrefix = prefix
+	}
+}
+
+// WithGoroutineHistory samples the goroutine count every interval (default
+// 1m) in the background, keeping the last samples counts (default 60), and
+// serves them at /goroutines/history. The sampler is stopped by Shutdown.
+func WithGoroutineHistory(interval time.Duration, samples int) Option {
+	return func(c *config) {
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		if samples <= 0 {
+			samples = 60
+		}
+		c.goroutineInterval = interval
+		c.goroutineSamples = samples
+	}
+}
+
+// WithRequestLog keeps the method, path, status, duration and time of the
+// last size requests (default 100) and serves them at /requests. The
+// endpoints are only registered together with WithBasicAuth, and a later
+// call with another size resizes the log, keeping the newest entries. Query
+// strings, headers and client addresses are not recorded, but paths can
+// still carry identifiers such as user IDs.
+func WithRequestLog(size int) Option {
+	return func(c *config) {
+		if size <= 0 {
+			size = 100
+		}
+		c.requestLogSize = size
+	}
+}
+
+// WithSampleRate records only about fraction of requests in the request
+// metrics, scaling each recorded request up so totals stay approximately
+// right. The fraction is rounded to 1/N, e.g. 0.01 records one request in a
+// hundred. 5xx responses and requests over WithSlowThreshold are always
+// recorded. A sampled-out request skips all per-request work: the Prometheus
+// histograms, observers, request log and dimensions see only recorded
+// requests, and only the /metrics counters are scaled up.
+func WithSampleRate(fraction float64) Option {
+	return func(c *config) {
+		if fraction <= 0 || fraction > 1 {
+			log.Printf("osinfo: invalid sample rate %v, recording every request", fraction)
+			c.sampleEvery = 1
+			return
+		}
+		c.sampleEvery = int64(math.Round(1 / fraction))
+	}
+}
+
+// WithLogger sets the structured logger used for request warnings such as
+// WithSlowThreshold (default slog.Default()).
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		if l != nil {
+			c.logger = l
+		}
+	}
+}
+
+// WithSlowThreshold logs a warning with the method, path, status and
+// duration of every measured request that takes longer than d. Disabled by
+// default.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(c *config) {
+		c.slowThreshold = d
+	}
+}
+
+// WithReadinessDelay makes /readyz report 503 for d after startup, or after
+// MarkReady is called, even when the checks pass, so load balancers hold
+// traffic while caches warm up.
+func WithReadinessDelay(d time.Duration) Option {
+	return func(c *config) {
+		c.readinessDelay = d
+	}
+}
+
+// WithWaitForReady makes /readyz report 503 until the application calls
+// MarkReady.
+func WithWaitForReady() Option {
+	return func(c *config) {
+		c.waitForReady = true
+	}
+}
+
+// WithEmptyHealthBody makes the /health liveness probe answer 204 No Content
+// instead of a JSON body, for probes and load balancers that prefer it.
+func WithEmptyHealthBody() Option {
+	return func(c *config) {
+		c.emptyHealthBody = true
+	}
+}
+
+// WithHealthDetails adds a compact summary to the /health body: server
+// uptime, total requests, CPU percent and memory used percent. Metrics that
+// can't be read are left out; /health itself still answers 200.
+// WithEmptyHealthBody takes precedence.
+func WithHealthDetails() Option {
+	return func(c *config) {
+		c.healthDetails = true
+	}
+}
+
+// WithDashboardLayout sets which dashboard panels are shown and in what
+// order, e.g. []string{"disk", "mem", "cpu"}. Cards and charts keep their
+// own rows. Panels are health, cpu, mem, disk, network, requests, latency,
+// custom, endpoints, cpu_chart, mem_chart and requests_chart; unknown names
+// are ignored.
+func WithDashboardLayout(panels []string) Option {
+	return func(c *config) {
+		for _, p := range panels {
+			if !slices.ContainsFunc(dashboardPanels, func(d dashboardPanel) bool { return d.name == p }) {
+				log.Printf("osinfo: unknown dashboard panel %q, ignoring", p)
+			}
+		}
+		c.dashboardLayout = append([]string{}, panels...)
+	}
+}
+
+// WithDashboardRefresh sets how often the dashboard polls for new data while
+// its tab is visible (default 2s, minimum 500ms). Hidden tabs don't poll.
+func WithDashboardRefresh(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.dashboardRefresh = max(d, 500*time.Millisecond)
+		}
+	}
+}
+
+// WithMaxStreamClients caps the concurrent connections to each streaming
+// endpoint, /dashboard-stream and /requests/stream (default 64); further
+// clients get a 503 until one disconnects. Dashboard clients share one
+// sampler however many are connected.
+func WithMaxStreamClients(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxStreamClients = n
+		}
+	}
+}
+
+// WithDailyReset reports today_requests in /metrics, the number of requests
+// since the last midnight in loc (time.Local when nil). total_requests keeps
+// counting since startup. The reset goroutine stops on Shutdown.
+func WithDailyReset(loc *time.Location) Option {
+	return func(c *config) {
+		if loc == nil {
+			loc = time.Local
+		}
+		c.dailyReset = loc
+	}
+}
+
+// WithLatencyHalfLife sets the half-life of the decaying average behind
+// avg_response_time_ms in /metrics (default 1m): a request's weight in the
+// average halves every half-life.
+func WithLatencyHalfLife(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.latencyHalfLife = d
+		}
+	}
+}
+
+// WithIdentityEnv changes which environment variables /identity reads, keyed
+// by field, e.g. {"pod_name": "MY_POD"}. Fields not mentioned keep their
+// defaults (POD_NAME, POD_NAMESPACE, POD_IP, NODE_NAME), new fields are
+// added, and an empty variable name removes a field.
+func WithIdentityEnv(vars map[string]string) Option {
+	return func(c *config) {
+		for field, env := range vars {
+			if env == "" {
+				delete(c.identityEnv, field)
+				continue
+			}
+			c.identityEnv[field] = env
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package osinfootel exports the osinfo request metrics and host gauges as
+// OpenTelemetry instruments. It lives in its own package so that only
+// applications importing it depend on the OpenTelemetry API.
+//
+//	osinfo.RegisterRoutes(r, "/os", osinfootel.WithMeter(otel.Meter("osinfo")))
+package osinfootel
+
+import (
+	"context"
+	"log"
+	"time"
+
+	osinfo "github.com/raza001/go-osinfo-gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// WithMeter records every request counted by osinfo on an
+// osinfo.requests counter and an osinfo.request.duration histogram (in
+// seconds), with the method, route and status as attributes, and reports
+// the host gauges as osinfo.cpu.used_percent, osinfo.memory.used_percent and
+// osinfo.disk.root.used_percent. The Prometheus metrics are unaffected.
+//
+// Instruments are created when WithMeter is called; if the meter rejects
+// them the error is logged and nothing is recorded.
+func WithMeter(meter metric.Meter) osinfo.Option {
+	requests, err := meter.Int64Counter("osinfo.requests",
+		metric.WithDescription("HTTP requests handled."),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		log.Printf("osinfootel: creating request counter: %v", err)
+		return osinfo.WithRequestObserver(nil)
+	}
+	duration, err := meter.Float64Histogram("osinfo.request.duration",
+		metric.WithDescription("Latency of HTTP requests."),
+		metric.WithUnit("s"))
+	if err != nil {
+		log.Printf("osinfootel: creating request histogram: %v", err)
+		return osinfo.WithRequestObserver(nil)
+	}
+	if err := registerGauges(meter); err != nil {
+		log.Printf("osinfootel: registering system gauges: %v", err)
+	}
+
+	return osinfo.WithRequestObserver(func(method, route string, status int, elapsed time.Duration) {
+		attrs := metric.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		ctx := context.Background()
+		requests.Add(ctx, 1, attrs)
+		duration.Record(ctx, elapsed.Seconds(), attrs)
+	})
+}
+
+// gaugeNames maps osinfo.SystemGauges keys to instrument names
+var gaugeNames = map[string]string{
+	"cpu_percent":            "osinfo.cpu.used_percent",
+	"mem_used_percent":       "osinfo.memory.used_percent",
+	"disk_root_used_percent": "osinfo.disk.root.used_percent",
+}
+
+// registerGauges creates the host gauges and a single callback that samples
+// them together on each collection
+func registerGauges(meter metric.Meter) error {
+	gauges := make(map[string]metric.Float64ObservableGauge, len(gaugeNames))
+	observables := make([]metric.Observable, 0, len(gaugeNames))
+	for key, name := range gaugeNames {
+		g, err := meter.Float64ObservableGauge(name, metric.WithUnit("%"))
+		if err != nil {
+			return err
+		}
+		gauges[key] = g
+		observables = append(observables, g)
+	}
+	_, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for key, v := range osinfo.SystemGauges() {
+			if g, ok := gauges[key]; ok {
+				o.ObserveFloat64(g, v)
+			}
+		}
+		return nil
+	}, observables...)
+	return err
+}
@@ -0,0 +1,67 @@
+package osinfo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pressureResources are the /proc/pressure files reported by /pressure
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// pressureHandler reports pressure stall information: for each resource the
+// share of time some (or all, "full") tasks were stalled over the last 10, 60
+// and 300 seconds, and the total stall time in microseconds. It answers 501
+// off Linux or when the kernel was built without PSI.
+func pressureHandler(c *gin.Context) {
+	out := gin.H{}
+	for _, res := range pressureResources {
+		b, err := readProcFile("/proc/pressure/" + res)
+		if errors.Is(err, errProcUnsupported) || errors.Is(err, os.ErrNotExist) {
+			respond(c, http.StatusNotImplemented, gin.H{"error": "pressure stall information is not available"})
+			return
+		}
+		if err != nil {
+			collectorError(c, err)
+			return
+		}
+		stats, err := parsePressure(b)
+		if err != nil {
+			collectorError(c, fmt.Errorf("/proc/pressure/%s: %w", res, err))
+			return
+		}
+		out[res] = stats
+	}
+	respond(c, http.StatusOK, out)
+}
+
+// parsePressure parses lines such as
+// "some avg10=0.12 avg60=0.05 avg300=0.01 total=123456"
+func parsePressure(b []byte) (map[string]map[string]float64, error) {
+	out := map[string]map[string]float64{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		vals := map[string]float64{}
+		for _, f := range fields[1:] {
+			k, v, ok := strings.Cut(f, "=")
+			if !ok {
+				return nil, fmt.Errorf("malformed field %q", f)
+			}
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed field %q", f)
+			}
+			vals[k] = n
+		}
+		out[fields[0]] = vals
+	}
+	return out, nil
+}
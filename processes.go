@@ -0,0 +1,102 @@
+package osinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// processFields maps the ?fields= names to the calls that gather them. Only
+// requested fields are read, since each is a separate syscall per process.
+var processFields = map[string]func(p *process.Process, out gin.H){
+	"pid": func(p *process.Process, out gin.H) {
+		out["pid"] = p.Pid
+	},
+	"name": func(p *process.Process, out gin.H) {
+		out["name"], _ = p.Name()
+	},
+	"status": func(p *process.Process, out gin.H) {
+		if st, err := p.Status(); err == nil && len(st) > 0 {
+			out["status"] = st[0]
+		}
+	},
+	"cpu": func(p *process.Process, out gin.H) {
+		out["cpu_percent"], _ = p.CPUPercent()
+	},
+	"mem": func(p *process.Process, out gin.H) {
+		out["memory_percent"], _ = p.MemoryPercent()
+	},
+}
+
+var defaultProcessFields = []string{"pid", "name", "status", "cpu", "mem"}
+
+// processesHandler streams the process list as a JSON array, gathering each
+// entry just before it is written so memory stays bounded by the limit.
+// ?fields=pid,name,cpu selects which fields are gathered. The list is not
+// cached: its fields vary per request and it is not kept in memory.
+func processesHandler(c *gin.Context) {
+	fields := defaultProcessFields
+	if q := c.Query("fields"); q != "" {
+		fields = nil
+		for _, f := range strings.Split(q, ",") {
+			f = strings.TrimSpace(f)
+			if _, ok := processFields[f]; !ok {
+				respond(c, http.StatusBadRequest, gin.H{
+					"error":     "unknown field: " + f,
+					"available": defaultProcessFields,
+				})
+				return
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	res, err := runCollector("processes", func() (any, error) {
+		return sys.Pids()
+	})
+	if err != nil {
+		collectorError(c, err)
+		return
+	}
+	pids := res.([]int32)
+
+	limit := cfg().processLimit
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 && n < limit {
+		limit = n
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	setCacheControl(c)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	c.Writer.WriteString("[")
+	written := 0
+	for _, pid := range pids {
+		if written >= limit {
+			break
+		}
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			// process exited since the pid list was read
+			continue
+		}
+		entry := make(gin.H, len(fields))
+		for _, f := range fields {
+			processFields[f](p, entry)
+		}
+
+		if written > 0 {
+			c.Writer.WriteString(",")
+		}
+		if err := enc.Encode(entry); err != nil {
+			return
+		}
+		written++
+	}
+	c.Writer.WriteString("]")
+}
@@ -0,0 +1,37 @@
+package osinfo
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errProcUnsupported is returned by readProcFile outside Linux
+var errProcUnsupported = errors.New("proc files are only available on Linux")
+
+// procFileHandler serves the parsed contents of a /proc or /sys file
+// registered with WithProcFile. The file is read on every request.
+func procFileHandler(path string, parser func([]byte) (any, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, err := readProcFile(path)
+		switch {
+		case errors.Is(err, errProcUnsupported):
+			respond(c, http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, os.ErrNotExist):
+			respond(c, http.StatusNotFound, gin.H{"error": "no such file: " + path})
+			return
+		case err != nil:
+			collectorError(c, err)
+			return
+		}
+		v, err := parser(b)
+		if err != nil {
+			respond(c, http.StatusInternalServerError, gin.H{"error": "parsing " + path + ": " + err.Error()})
+			return
+		}
+		respond(c, http.StatusOK, v)
+	}
+}
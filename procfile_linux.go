@@ -0,0 +1,9 @@
+//go:build linux
+
+package osinfo
+
+import "os"
+
+func readProcFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
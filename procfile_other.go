@@ -0,0 +1,7 @@
+//go:build !linux
+
+package osinfo
+
+func readProcFile(string) ([]byte, error) {
+	return nil, errProcUnsupported
+}
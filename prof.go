@@ -0,0 +1,34 @@
+package osinfo
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heapProfileHandler downloads a heap profile for `go tool pprof`. ?gc=1 runs
+// a garbage collection first so the profile reflects live objects.
+func heapProfileHandler(c *gin.Context) {
+	if gc, _ := strconv.ParseBool(c.Query("gc")); gc {
+		runtime.GC()
+	}
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", `attachment; filename="heap.pprof"`)
+	c.Status(http.StatusOK)
+	if err := pprof.WriteHeapProfile(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+// goroutineDumpHandler writes the stacks of all goroutines as text, which is
+// usually enough to spot a deadlock
+func goroutineDumpHandler(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := pprof.Lookup("goroutine").WriteTo(c.Writer, 2); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,255 @@
+package osinfo
+
+import (
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// The histograms are set by the first RegisterRoutes call that needs them
+// and read on every request, possibly while another call registers
+var (
+	requestDuration   atomic.Pointer[prometheus.HistogramVec]
+	dimensionDuration atomic.Pointer[prometheus.HistogramVec]
+)
+
+// promRegistered holds the settings the collectors were first registered
+// with. The default registry can't change a collector's buckets or labels
+// afterwards, so later RegisterRoutes calls keep them.
+var promRegistered *promSettings
+
+type promSettings struct {
+	namespace string
+	buckets   []float64
+	labels    map[string]string
+}
+
+func (s *promSettings) equal(o *promSettings) bool {
+	return s.namespace == o.namespace &&
+		slices.Equal(s.buckets, o.buckets) &&
+		maps.Equal(s.labels, o.labels)
+}
+
+// registerPrometheus registers the request latency histogram and the system
+// gauge collector with the default registry, plus the per-dimension
+// histogram with WithDimensionLabels. Later calls keep the first call's
+// collectors and log when the namespace, buckets or constant labels asked
+// for differ from theirs.
+func registerPrometheus(c *config) {
+	want := &promSettings{c.metricNamespace, slices.Clone(c.latencyBuckets), maps.Clone(c.constLabels)}
+	if promRegistered == nil {
+		promRegistered = want
+		requestDuration.Store(registerHistogram(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   want.namespace,
+			Subsystem:   "osinfo",
+			Name:        "request_duration_seconds",
+			Help:        "Latency of HTTP requests in seconds.",
+			Buckets:     want.buckets,
+			ConstLabels: want.labels,
+		}, []string{"method", "route", "status"})))
+
+		if err := prometheus.Register(newSystemMetricsCollector(want.namespace, want.labels)); err != nil {
+			var are prometheus.AlreadyRegisteredError
+			if !errors.As(err, &are) {
+				log.Printf("osinfo: registering system collector: %v", err)
+			}
+		}
+	} else if !promRegistered.equal(want) {
+		log.Printf("osinfo: Prometheus metrics are already registered; the changed namespace, latency buckets or constant labels are ignored")
+	}
+
+	if c.dimensionLabels && len(c.dimensions) > 0 && dimensionDuration.Load() == nil {
+		dimensionDuration.Store(registerHistogram(prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   promRegistered.namespace,
+			Subsystem:   "osinfo",
+			Name:        "dimension_request_duration_seconds",
+			Help:        "Latency of HTTP requests in seconds by WithDimension value.",
+			Buckets:     promRegistered.buckets,
+			ConstLabels: promRegistered.labels,
+		}, []string{"dimension", "value"})))
+	}
+}
+
+// registerHistogram registers h, or returns the equal histogram already
+// registered. It returns nil when h can't be registered.
+func registerHistogram(h *prometheus.HistogramVec) *prometheus.HistogramVec {
+	err := prometheus.Register(h)
+	if err == nil {
+		return h
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(*prometheus.HistogramVec); ok {
+			return existing
+		}
+	}
+	return nil
+}
+
+// promHandler serves the default registry, offering OpenMetrics to scrapers
+// that negotiate it when WithOpenMetrics is set
+func promHandler() http.Handler {
+	if !cfg().openMetrics {
+		return promhttp.Handler()
+	}
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+}
+
+// systemMetricsCollector exports host gauges, sampled fresh on every scrape
+type systemMetricsCollector struct {
+	cpuPercent  *prometheus.Desc
+	memPercent  *prometheus.Desc
+	memUsed     *prometheus.Desc
+	memTotal    *prometheus.Desc
+	diskPercent *prometheus.Desc
+}
+
+func newSystemMetricsCollector(namespace string, labels prometheus.Labels) *systemMetricsCollector {
+	name := func(n string) string {
+		return prometheus.BuildFQName(namespace, "osinfo", n)
+	}
+	return &systemMetricsCollector{
+		cpuPercent: prometheus.NewDesc(name("cpu_used_percent"),
+			"CPU utilisation since the previous sample.", nil, labels),
+		memPercent: prometheus.NewDesc(name("memory_used_percent"),
+			"Percentage of memory in use.", nil, labels),
+		memUsed: prometheus.NewDesc(name("memory_used_bytes"),
+			"Memory in use in bytes.", nil, labels),
+		memTotal: prometheus.NewDesc(name("memory_total_bytes"),
+			"Total memory in bytes.", nil, labels),
+		diskPercent: prometheus.NewDesc(name("disk_used_percent"),
+			"Percentage of disk space in use.", []string{"mountpoint", "device", "fstype"}, labels),
+	}
+}
+
+func (s *systemMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.cpuPercent
+	ch <- s.memPercent
+	ch <- s.memUsed
+	ch <- s.memTotal
+	ch <- s.diskPercent
+}
+
+func (s *systemMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	if p, err := instantCPUPercent(&prometheusCPU); err == nil && len(p) > 0 {
+		ch <- prometheus.MustNewConstMetric(s.cpuPercent, prometheus.GaugeValue, p[0])
+	}
+	if m, err := sys.VirtualMemory(); err == nil {
+		ch <- prometheus.MustNewConstMetric(s.memPercent, prometheus.GaugeValue, m.UsedPercent)
+		ch <- prometheus.MustNewConstMetric(s.memUsed, prometheus.GaugeValue, float64(m.Used))
+		ch <- prometheus.MustNewConstMetric(s.memTotal, prometheus.GaugeValue, float64(m.Total))
+	}
+	parts, err := reportedPartitions()
+	if err != nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, p := range parts {
+		// a mountpoint can be listed more than once; duplicate label sets
+		// would fail the whole scrape
+		if seen[p.Mountpoint] {
+			continue
+		}
+		seen[p.Mountpoint] = true
+		u, err := mountUsage(p.Mountpoint)
+		if err != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(s.diskPercent, prometheus.GaugeValue,
+			u.UsedPercent, p.Mountpoint, p.Device, p.Fstype)
+	}
+}
+
+func observeRequest(method, route string, status int, seconds float64) {
+	h := requestDuration.Load()
+	if h == nil {
+		return
+	}
+	h.WithLabelValues(method, route, strconv.Itoa(status)).Observe(seconds)
+}
+
+func observeDimension(name, value string, seconds float64) {
+	h := dimensionDuration.Load()
+	if h == nil {
+		return
+	}
+	h.WithLabelValues(name, value).Observe(seconds)
+}
+
+// promJSONHandler renders the default Prometheus registry as JSON for
+// consumers that can't parse the text exposition format
+func promJSONHandler(c *gin.Context) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil && len(families) == 0 {
+		respond(c, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(families))
+	for _, mf := range families {
+		samples := make([]gin.H, 0, len(mf.GetMetric()))
+		for _, m := range mf.GetMetric() {
+			samples = append(samples, promSample(mf.GetType(), m))
+		}
+		out = append(out, gin.H{
+			"name":    mf.GetName(),
+			"help":    mf.GetHelp(),
+			"type":    strings.ToLower(mf.GetType().String()),
+			"metrics": samples,
+		})
+	}
+	respond(c, http.StatusOK, out)
+}
+
+func promSample(t dto.MetricType, m *dto.Metric) gin.H {
+	labels := make(map[string]string, len(m.GetLabel()))
+	for _, l := range m.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	s := gin.H{"labels": labels}
+
+	switch t {
+	case dto.MetricType_COUNTER:
+		s["value"] = m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		s["value"] = m.GetGauge().GetValue()
+	case dto.MetricType_UNTYPED:
+		s["value"] = m.GetUntyped().GetValue()
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		buckets := make([]gin.H, 0, len(h.GetBucket()))
+		for _, b := range h.GetBucket() {
+			buckets = append(buckets, gin.H{
+				"upper_bound":      b.GetUpperBound(),
+				"cumulative_count": b.GetCumulativeCount(),
+			})
+		}
+		s["count"] = h.GetSampleCount()
+		s["sum"] = h.GetSampleSum()
+		s["buckets"] = buckets
+	case dto.MetricType_SUMMARY:
+		sm := m.GetSummary()
+		quantiles := make([]gin.H, 0, len(sm.GetQuantile()))
+		for _, q := range sm.GetQuantile() {
+			quantiles = append(quantiles, gin.H{
+				"quantile": q.GetQuantile(),
+				"value":    q.GetValue(),
+			})
+		}
+		s["count"] = sm.GetSampleCount()
+		s["sum"] = sm.GetSampleSum()
+		s["quantiles"] = quantiles
+	}
+	return s
+}
@@ -0,0 +1,195 @@
+package osinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestRecord is one entry of the request log. Only the path is kept, not
+// the query string, headers or client address.
+type requestRecord struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMs float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// requestRing keeps the most recent requests in a fixed-size ring
+type requestRing struct {
+	mu      sync.Mutex
+	records []requestRecord
+	next    int
+	full    bool
+}
+
+func (r *requestRing) add(rec requestRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// resize changes the capacity to size, keeping the most recent records
+func (r *requestRing) resize(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if size == len(r.records) {
+		return
+	}
+	recent := r.ordered()
+	if len(recent) > size {
+		recent = recent[len(recent)-size:]
+	}
+	r.records = make([]requestRecord, size)
+	r.next = copy(r.records, recent) % size
+	r.full = len(recent) == size
+}
+
+// snapshot returns the records oldest first
+func (r *requestRing) snapshot() []requestRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ordered()
+}
+
+// ordered returns a copy of the records oldest first. r.mu must be held.
+func (r *requestRing) ordered() []requestRecord {
+	if !r.full {
+		return append([]requestRecord{}, r.records[:r.next]...)
+	}
+	return append(append([]requestRecord{}, r.records[r.next:]...), r.records[:r.next]...)
+}
+
+// requestLog holds nil unless WithRequestLog is set. It is atomic because
+// the middleware reads it on every request while a later RegisterRoutes
+// call may create it.
+var requestLog atomic.Pointer[requestRing]
+
+// requestsHandler returns the logged requests, oldest first. ?status=5xx
+// keeps a status class, ?status=404 an exact code.
+func requestsHandler(c *gin.Context) {
+	match, ok := statusMatcher(c.Query("status"))
+	if !ok {
+		respond(c, http.StatusBadRequest, gin.H{"error": "invalid status filter: " + c.Query("status")})
+		return
+	}
+	out := []requestRecord{}
+	for _, rec := range requestLog.Load().snapshot() {
+		if match(rec.Status) {
+			out = append(out, rec)
+		}
+	}
+	respond(c, http.StatusOK, gin.H{"requests": out})
+}
+
+// statusMatcher parses a status filter: "" matches everything, "4xx" a
+// class and "404" a single code
+func statusMatcher(q string) (func(int) bool, bool) {
+	if q == "" {
+		return func(int) bool { return true }, true
+	}
+	if class, ok := strings.CutSuffix(strings.ToLower(q), "xx"); ok {
+		n, err := strconv.Atoi(class)
+		if err != nil || n < 1 || n > 5 {
+			return nil, false
+		}
+		return func(s int) bool { return s/100 == n }, true
+	}
+	code, err := strconv.Atoi(q)
+	if err != nil || code < 100 || code > 599 {
+		return nil, false
+	}
+	return func(s int) bool { return s == code }, true
+}
+
+// requestStreams fans completed requests out to the /requests/stream
+// clients. Each client has a small buffer; when it is full the record is
+// dropped for that client so a slow reader never delays request handling.
+var requestStreams = struct {
+	mu      sync.RWMutex
+	clients map[chan requestRecord]struct{}
+	count   atomic.Int32
+}{clients: make(map[chan requestRecord]struct{})}
+
+func publishRequest(rec requestRecord) {
+	if requestStreams.count.Load() == 0 {
+		return
+	}
+	requestStreams.mu.RLock()
+	defer requestStreams.mu.RUnlock()
+	for ch := range requestStreams.clients {
+		select {
+		case ch <- rec:
+		default:
+		}
+	}
+}
+
+// requestsStreamHandler writes each completed request as a JSON line
+// (application/x-ndjson) until the client disconnects. Connections are
+// capped by WithMaxStreamClients, counted apart from /dashboard-stream.
+func requestsStreamHandler(c *gin.Context) {
+	match, ok := statusMatcher(c.Query("status"))
+	if !ok {
+		respond(c, http.StatusBadRequest, gin.H{"error": "invalid status filter: " + c.Query("status")})
+		return
+	}
+	if refuseStream(c) {
+		return
+	}
+
+	ch := make(chan requestRecord, 64)
+	requestStreams.mu.Lock()
+	if len(requestStreams.clients) >= cfg().maxStreamClients {
+		requestStreams.mu.Unlock()
+		c.Header("Retry-After", "10")
+		respond(c, http.StatusServiceUnavailable, gin.H{"error": "too many streaming clients"})
+		return
+	}
+	requestStreams.clients[ch] = struct{}{}
+	requestStreams.count.Add(1)
+	requestStreams.mu.Unlock()
+	defer func() {
+		requestStreams.mu.Lock()
+		delete(requestStreams.clients, ch)
+		requestStreams.count.Add(-1)
+		requestStreams.mu.Unlock()
+	}()
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no")
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	enc := json.NewEncoder(c.Writer)
+	ctx := c.Request.Context()
+	for {
+		select {
+		case rec := <-ch:
+			if !match(rec.Status) {
+				continue
+			}
+			if err := enc.Encode(rec); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-ctx.Done():
+			return
+		case <-streamsDone():
+			return
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package osinfo
+
+import (
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respond writes data as JSON. Successful responses are trimmed to the fields
+// configured with WithFields. Percentage fields are rounded to the
+// configured precision unless the request asks for ?raw=true, NaN and Inf
+// values (which encoding/json rejects) become null, and the payload is
+// wrapped in an envelope when WithEnvelope is set.
+func respond(c *gin.Context, status int, data any) {
+	setCacheControl(c)
+	if fields, ok := c.Get(fieldsKey); ok && status < http.StatusBadRequest {
+		data = projectFields(data, fields.(map[string]bool))
+	}
+	if raw, _ := strconv.ParseBool(c.Query("raw")); !raw {
+		data = roundPercents(data, false)
+	}
+	if clean, replaced := replaceNonFinite(data); replaced {
+		data = clean
+		if h, ok := data.(gin.H); ok {
+			h["nonfinite_replaced"] = true
+		}
+	}
+	if cfg().envelope {
+		meta := gin.H{
+			"timestamp": clk.Now(),
+			"endpoint":  c.FullPath(),
+			"hostname":  envelopeHostname,
+		}
+		if len(cfg().constLabels) > 0 {
+			meta["labels"] = cfg().constLabels
+		}
+		data = gin.H{"data": data, "meta": meta}
+	}
+	c.JSON(status, data)
+}
+
+var envelopeHostname, _ = os.Hostname()
+
+// setCacheControl applies the cache policy for dynamic responses, which is
+// no-store unless overridden with WithCacheControl
+func setCacheControl(c *gin.Context) {
+	c.Header("Cache-Control", cfg().cacheControl)
+	if strings.Contains(cfg().cacheControl, "no-store") || strings.Contains(cfg().cacheControl, "no-cache") {
+		c.Header("Pragma", "no-cache")
+	}
+}
+
+// roundPercents returns a copy of v with every float under a key containing
+// "percent" rounded. inPercent is set once such a key has been seen.
+func roundPercents(v any, inPercent bool) any {
+	switch t := v.(type) {
+	case gin.H:
+		out := make(gin.H, len(t))
+		for k, val := range t {
+			out[k] = roundPercents(val, inPercent || isPercentKey(k))
+		}
+		return out
+	case map[string]any:
+		return map[string]any(roundPercents(gin.H(t), inPercent).(gin.H))
+	case []gin.H:
+		out := make([]gin.H, len(t))
+		for i, val := range t {
+			out[i] = roundPercents(val, inPercent).(gin.H)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = roundPercents(val, inPercent)
+		}
+		return out
+	case []float64:
+		if !inPercent {
+			return t
+		}
+		out := make([]float64, len(t))
+		for i, f := range t {
+			out[i] = roundTo(f, cfg().percentDecimals)
+		}
+		return out
+	case float64:
+		if inPercent {
+			return roundTo(t, cfg().percentDecimals)
+		}
+	case float32:
+		if inPercent {
+			return roundTo(float64(t), cfg().percentDecimals)
+		}
+	}
+	return v
+}
+
+func isPercentKey(k string) bool {
+	return strings.Contains(strings.ToLower(k), "percent")
+}
+
+func roundTo(f float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(f*p) / p
+}
+
+// replaceNonFinite returns v with NaN and Inf floats replaced by nil, and
+// whether anything was replaced. v is only copied when something is.
+func replaceNonFinite(v any) (any, bool) {
+	nonFinite := func(f float64) bool { return math.IsNaN(f) || math.IsInf(f, 0) }
+	switch t := v.(type) {
+	case gin.H:
+		var out gin.H
+		for k, val := range t {
+			clean, replaced := replaceNonFinite(val)
+			if !replaced {
+				continue
+			}
+			if out == nil {
+				out = make(gin.H, len(t))
+				for k2, v2 := range t {
+					out[k2] = v2
+				}
+			}
+			out[k] = clean
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case map[string]any:
+		out, replaced := replaceNonFinite(gin.H(t))
+		return map[string]any(out.(gin.H)), replaced
+	case []gin.H:
+		var out []gin.H
+		for i, val := range t {
+			clean, replaced := replaceNonFinite(val)
+			if !replaced {
+				continue
+			}
+			if out == nil {
+				out = append([]gin.H(nil), t...)
+			}
+			out[i] = clean.(gin.H)
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case []any:
+		var out []any
+		for i, val := range t {
+			clean, replaced := replaceNonFinite(val)
+			if !replaced {
+				continue
+			}
+			if out == nil {
+				out = append([]any(nil), t...)
+			}
+			out[i] = clean
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case map[string]float64:
+		var out map[string]any
+		for k, f := range t {
+			if !nonFinite(f) {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]any, len(t))
+				for k2, f2 := range t {
+					out[k2] = f2
+				}
+			}
+			out[k] = nil
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case []float64:
+		if !slices.ContainsFunc(t, nonFinite) {
+			return v, false
+		}
+		out := make([]any, len(t))
+		for i, f := range t {
+			if !nonFinite(f) {
+				out[i] = f
+			}
+		}
+		return out, true
+	case float64:
+		if nonFinite(t) {
+			return nil, true
+		}
+	case float32:
+		if nonFinite(float64(t)) {
+			return nil, true
+		}
+	}
+	return v, false
+}
+
+// fieldsKey holds the WithFields selection for the matched endpoint
+const fieldsKey = "osinfo.fields"
+
+// selectFields tells respond which fields the endpoint may return
+func selectFields(fields []string) gin.HandlerFunc {
+	set := fieldSet(fields)
+	return func(c *gin.Context) {
+		c.Set(fieldsKey, set)
+		c.Next()
+	}
+}
+
+func fieldSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		set[f] = true
+	}
+	return set
+}
+
+// projectSection applies the WithFields selection of the endpoint /name to
+// a section of an aggregate response such as /batch or /dashboard-data, so
+// a field hidden from /mem is hidden from the mem section too
+func projectSection(name string, data any) any {
+	if fields, ok := cfg().fields["/"+name]; ok {
+		return projectFields(data, fieldSet(fields))
+	}
+	return data
+}
+
+// projectFields keeps only the selected keys of an object, or of each object
+// in a list. Other values are returned unchanged.
+func projectFields(v any, fields map[string]bool) any {
+	switch v := v.(type) {
+	case gin.H:
+		return gin.H(projectMap(v, fields))
+	case map[string]any:
+		return projectMap(v, fields)
+	case []gin.H:
+		out := make([]gin.H, len(v))
+		for i, e := range v {
+			out[i] = projectMap(e, fields)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = projectFields(e, fields)
+		}
+		return out
+	}
+	return v
+}
+
+// projectionKept are added by osinfo itself and survive any selection
+var projectionKept = map[string]bool{"cached_at": true, "age_ms": true}
+
+// projectMap keeps the selected keys of m. Degraded and error objects,
+// including failed list entries such as a hung mount, are kept whole so the
+// reason isn't trimmed away, and the partitions list of a /disk response
+// limited with WithMaxPartitions is trimmed per partition.
+func projectMap(m map[string]any, fields map[string]bool) map[string]any {
+	if _, ok := m["error"]; ok {
+		return m
+	}
+	if _, ok := m["degraded"]; ok {
+		return m
+	}
+	if list, ok := m["partitions"]; ok && !fields["partitions"] {
+		out := maps.Clone(m)
+		out["partitions"] = projectFields(list, fields)
+		return out
+	}
+	out := make(map[string]any, len(fields))
+	for k, e := range m {
+		if fields[k] || projectionKept[k] {
+			out[k] = e
+		}
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package osinfo
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// nonFiniteCollector reports a memory percentage such as a host can give
+// right after boot, before its counters settle
+type nonFiniteCollector struct {
+	gopsutilCollector
+	usedPercent float64
+}
+
+func (f nonFiniteCollector) VirtualMemory() (*mem.VirtualMemoryStat, error) {
+	return &mem.VirtualMemoryStat{Total: 100, UsedPercent: f.usedPercent}, nil
+}
+
+func TestNonFiniteValuesBecomeNull(t *testing.T) {
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		t.Run(strconv.FormatFloat(v, 'g', -1, 64), func(t *testing.T) {
+			r := newTestEngine(t, "/os")
+			sys = nonFiniteCollector{usedPercent: v}
+
+			w := get(r, "/os/mem")
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /os/mem = %d, want 200: %s", w.Code, w.Body)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := body["usedPercent"]; !ok || got != nil {
+				t.Errorf("usedPercent = %v, want null", got)
+			}
+			if body["total"] != float64(100) {
+				t.Errorf("total = %v, want 100 left as is", body["total"])
+			}
+			if body["nonfinite_replaced"] != true {
+				t.Errorf("nonfinite_replaced = %v, want true", body["nonfinite_replaced"])
+			}
+		})
+	}
+}
+
+// twoDisks reports two mounted filesystems
+type twoDisks struct{ gopsutilCollector }
+
+func (twoDisks) Partitions(bool) ([]disk.PartitionStat, error) {
+	return []disk.PartitionStat{
+		{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+		{Device: "/dev/sdb1", Mountpoint: "/data", Fstype: "ext4"},
+	}, nil
+}
+
+func (twoDisks) DiskUsage(path string) (*disk.UsageStat, error) {
+	return &disk.UsageStat{Path: path, Total: 100, Used: 25, UsedPercent: 25}, nil
+}
+
+func decode(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestFieldsKeepDegradedBody(t *testing.T) {
+	r := newTestEngine(t, "/os", WithFields("/mem", "usedPercent"))
+	sys = restrictedCollector{}
+
+	body := decode(t, get(r, "/os/mem").Body.Bytes())
+	for _, k := range []string{"degraded", "note", "error"} {
+		if _, ok := body[k]; !ok {
+			t.Errorf("degraded body lost %q: %v", k, body)
+		}
+	}
+}
+
+func TestFieldsProjectPartitionsWrapper(t *testing.T) {
+	r := newTestEngine(t, "/os", WithMaxPartitions(1), WithFields("/disk", "mountpoint"))
+	sys = twoDisks{}
+
+	body := decode(t, get(r, "/os/disk").Body.Bytes())
+	parts, _ := body["partitions"].([]any)
+	if len(parts) != 1 || body["total"] != float64(2) || body["truncated"] != true {
+		t.Fatalf("body = %v, want one partition of two, truncated", body)
+	}
+	if p := parts[0].(map[string]any); len(p) != 1 || p["mountpoint"] != "/" {
+		t.Errorf("partition = %v, want only its mountpoint", p)
+	}
+}
+
+func TestFieldsKeepCacheAge(t *testing.T) {
+	r := newTestEngine(t, "/os", WithCacheTTL(time.Minute), WithFields("/disk", "mountpoint"), WithMaxPartitions(5))
+	sys = twoDisks{}
+
+	get(r, "/os/disk")
+	body := decode(t, get(r, "/os/disk").Body.Bytes())
+	if _, ok := body["age_ms"]; !ok {
+		t.Errorf("cached body = %v, want age_ms kept", body)
+	}
+	if _, ok := body["cached_at"]; !ok {
+		t.Errorf("cached body = %v, want cached_at kept", body)
+	}
+}
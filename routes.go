@@ -0,0 +1,114 @@
+package osinfo
+
+import (
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// endpointDescriptions describes the built-in endpoints for /routes
+var endpointDescriptions = map[string]string{
+	"/health":             "liveness check",
+	"/readyz":             "readiness: health checks and thresholds",
+	"/status":             "plain-text OK/FAIL for uptime monitors",
+	"/info":               "host information",
+	"/uptime":             "host and server uptime",
+	"/mem":                "memory usage",
+	"/cpu":                "CPU utilisation",
+	"/disk":               "disk partitions and usage",
+	"/env":                "environment variables",
+	"/metrics":            "request statistics",
+	"/metrics/csv":        "per-route statistics as CSV",
+	"/server-uptime":      "server uptime",
+	"/gui-metrics":        "Prometheus metrics",
+	"/gui-metrics/json":   "Prometheus metrics as JSON",
+	"/dashboard":          "dashboard UI",
+	"/dashboard/export":   "dashboard as a standalone HTML snapshot",
+	"/dashboard-data":     "data rendered by the dashboard",
+	"/dashboard-stream":   "dashboard data as server-sent events",
+	"/static/*filepath":   "dashboard assets",
+	"/network":            "network IO counters",
+	"/network/interfaces": "per-interface counters and rates",
+	"/collectors":         "collector status and circuit breakers",
+	"/processes":          "running processes",
+	"/load":               "load averages",
+	"/batch":              "selected collectors in one call",
+	"/threads":            "goroutines, OS threads and GOMAXPROCS",
+	"/sched":              "scheduler latency and goroutine count",
+	"/runtime":            "Go runtime metrics",
+	"/kernel":             "virtualization and kernel settings",
+	"/users":              "logged-in user sessions",
+	"/identity":           "pod and node identity",
+	"/pressure":           "pressure stall information",
+	"/version":            "build metadata",
+	"/routes":             "this listing",
+	"/debug/vars":         "expvar variables",
+	"/prof/heap":          "heap profile download",
+	"/goroutines/history": "sampled goroutine counts",
+	"/requests":           "recently handled requests",
+	"/requests/stream":    "live request log as JSON Lines",
+	"/prof/goroutine":     "goroutine stack dump",
+}
+
+// endpointInfo is one entry of the /routes listing
+type endpointInfo struct {
+	Path        string   `json:"path"`
+	Methods     []string `json:"methods"`
+	Description string   `json:"description"`
+	Custom      bool     `json:"custom,omitempty"`
+}
+
+type customEndpoint struct {
+	path        string
+	description string
+	handler     gin.HandlerFunc
+}
+
+var registered = struct {
+	mu        sync.RWMutex
+	endpoints map[string]endpointInfo
+}{endpoints: make(map[string]endpointInfo)}
+
+// recordEndpoint adds a registered route to the /routes listing
+func recordEndpoint(base, relativePath, method, description string, custom bool) {
+	if description == "" && relativePath == cfg().dashboardPath {
+		description = endpointDescriptions["/dashboard"]
+	}
+	if description == "" {
+		description = endpointDescriptions[relativePath]
+	}
+	full := path.Join(base, relativePath)
+
+	registered.mu.Lock()
+	defer registered.mu.Unlock()
+	e, ok := registered.endpoints[full]
+	if !ok {
+		e = endpointInfo{Path: full, Description: description, Custom: custom}
+	}
+	if !slices.Contains(e.Methods, method) {
+		e.Methods = append(e.Methods, method)
+	}
+	registered.endpoints[full] = e
+}
+
+// routesHandler lists every endpoint osinfo registered, including custom ones
+func routesHandler(c *gin.Context) {
+	respond(c, http.StatusOK, registeredEndpoints())
+}
+
+// registeredEndpoints returns the /routes listing sorted by path
+func registeredEndpoints() []endpointInfo {
+	registered.mu.RLock()
+	out := make([]endpointInfo, 0, len(registered.endpoints))
+	for _, e := range registered.endpoints {
+		out = append(out, e)
+	}
+	registered.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
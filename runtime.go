@@ -0,0 +1,82 @@
+package osinfo
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// threadsHandler reports goroutines alongside the OS threads the Go runtime
+// has created. A high thread count usually points at blocking syscalls.
+func threadsHandler(c *gin.Context) {
+	respond(c, http.StatusOK, gin.H{
+		"goroutines": runtime.NumGoroutine(),
+		"os_threads": pprof.Lookup("threadcreate").Count(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+	})
+}
+
+// goroutineSample is one entry of the goroutine history
+type goroutineSample struct {
+	Time       time.Time `json:"time"`
+	Goroutines int       `json:"goroutines"`
+}
+
+// goroutineRing keeps the most recent goroutine counts in a fixed-size ring
+type goroutineRing struct {
+	mu      sync.Mutex
+	samples []goroutineSample
+	next    int
+	full    bool
+}
+
+func (r *goroutineRing) add(s goroutineSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the samples oldest first
+func (r *goroutineRing) snapshot() []goroutineSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]goroutineSample{}, r.samples[:r.next]...)
+	}
+	return append(append([]goroutineSample{}, r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+var goroutineHistory *goroutineRing
+
+// runGoroutineSampler records runtime.NumGoroutine every interval until stop
+// is closed
+func runGoroutineSampler(ring *goroutineRing, interval time.Duration, stop <-chan struct{}) {
+	ring.add(goroutineSample{Time: clk.Now(), Goroutines: runtime.NumGoroutine()})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case t := <-ticker.C:
+			ring.add(goroutineSample{Time: t, Goroutines: runtime.NumGoroutine()})
+		}
+	}
+}
+
+// goroutineHistoryHandler returns the sampled goroutine counts, oldest
+// first. A baseline that keeps rising across the window suggests a leak.
+func goroutineHistoryHandler(c *gin.Context) {
+	respond(c, http.StatusOK, gin.H{
+		"interval": cfg().goroutineInterval.String(),
+		"samples":  goroutineHistory.snapshot(),
+	})
+}
@@ -0,0 +1,91 @@
+package osinfo
+
+import (
+	"net/http"
+	rtmetrics "runtime/metrics"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// curatedRuntimeMetrics are the stable runtime/metrics reported by /runtime
+// by default, keyed by the name used in the response
+var curatedRuntimeMetrics = map[string]string{
+	"heap_live_bytes":   "/gc/heap/live:bytes",
+	"heap_goal_bytes":   "/gc/heap/goal:bytes",
+	"gc_cycles":         "/gc/cycles/total:gc-cycles",
+	"goroutines":        "/sched/goroutines:goroutines",
+	"alloc_bytes_total": "/gc/heap/allocs:bytes",
+	"alloc_objects":     "/gc/heap/allocs:objects",
+	"memory_total":      "/memory/classes/total:bytes",
+	"gc_cpu_seconds":    "/cpu/classes/gc/total:cpu-seconds",
+	"total_cpu_seconds": "/cpu/classes/total:cpu-seconds",
+	"gc_pauses":         "/sched/pauses/total/gc:seconds",
+}
+
+// runtimeHandler reports Go runtime statistics read with runtime/metrics.
+// By default it returns a curated set plus the derived gc_cpu_fraction and
+// alloc_rate_bytes_per_second (averaged since the server started);
+// ?all=true returns every metric the runtime supports under its own name.
+// Histograms are summarised as a count and percentiles.
+func runtimeHandler(c *gin.Context) {
+	if all, _ := strconv.ParseBool(c.Query("all")); all {
+		descs := rtmetrics.All()
+		samples := make([]rtmetrics.Sample, len(descs))
+		for i, d := range descs {
+			samples[i].Name = d.Name
+		}
+		rtmetrics.Read(samples)
+		out := gin.H{}
+		for _, s := range samples {
+			if v, ok := runtimeValue(s); ok {
+				out[s.Name] = v
+			}
+		}
+		respond(c, http.StatusOK, gin.H{"metrics": out})
+		return
+	}
+
+	keys := make([]string, 0, len(curatedRuntimeMetrics))
+	samples := make([]rtmetrics.Sample, 0, len(curatedRuntimeMetrics))
+	for key, name := range curatedRuntimeMetrics {
+		keys = append(keys, key)
+		samples = append(samples, rtmetrics.Sample{Name: name})
+	}
+	rtmetrics.Read(samples)
+	out := gin.H{}
+	for i, s := range samples {
+		if v, ok := runtimeValue(s); ok {
+			out[keys[i]] = v
+		}
+	}
+	if gc, ok := out["gc_cpu_seconds"].(float64); ok {
+		if total, ok := out["total_cpu_seconds"].(float64); ok && total > 0 {
+			out["gc_cpu_fraction"] = gc / total
+		}
+	}
+	if allocs, ok := out["alloc_bytes_total"].(uint64); ok {
+		if up := since(metrics.StartTime).Seconds(); up > 0 {
+			out["alloc_rate_bytes_per_second"] = float64(allocs) / up
+		}
+	}
+	respond(c, http.StatusOK, out)
+}
+
+// runtimeValue converts a sample for JSON. Metrics the running Go version
+// doesn't support are reported as KindBad and left out.
+func runtimeValue(s rtmetrics.Sample) (any, bool) {
+	switch s.Value.Kind() {
+	case rtmetrics.KindUint64:
+		return s.Value.Uint64(), true
+	case rtmetrics.KindFloat64:
+		return s.Value.Float64(), true
+	case rtmetrics.KindFloat64Histogram:
+		if strings.HasSuffix(s.Name, ":seconds") {
+			return histogramSummaryMs(s.Value.Float64Histogram()), true
+		}
+		return histogramSummary(s.Value.Float64Histogram(), "", 1), true
+	}
+	return nil, false
+}
@@ -0,0 +1,79 @@
+package osinfo
+
+import (
+	"math"
+	"net/http"
+	"runtime"
+	rtmetrics "runtime/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// schedHandler reports scheduler state: how many goroutines there are and
+// how long runnable goroutines waited before running since startup. Rising
+// scheduling latency shows up as tail latency before CPU looks saturated.
+func schedHandler(c *gin.Context) {
+	samples := []rtmetrics.Sample{
+		{Name: "/sched/goroutines:goroutines"},
+		{Name: "/sched/latencies:seconds"},
+	}
+	rtmetrics.Read(samples)
+
+	out := gin.H{
+		"gomaxprocs":    runtime.GOMAXPROCS(0),
+		"num_goroutine": runtime.NumGoroutine(),
+	}
+	if v := samples[0].Value; v.Kind() == rtmetrics.KindUint64 {
+		out["goroutines"] = v.Uint64()
+	}
+	if v := samples[1].Value; v.Kind() == rtmetrics.KindFloat64Histogram {
+		out["latencies"] = histogramSummaryMs(v.Float64Histogram())
+	}
+	respond(c, http.StatusOK, out)
+}
+
+// histogramSummaryMs summarises a runtime/metrics histogram of seconds as a
+// count and millisecond percentiles
+func histogramSummaryMs(h *rtmetrics.Float64Histogram) gin.H {
+	return histogramSummary(h, "_ms", 1000)
+}
+
+// histogramSummary reports the observation count and the p50, p90, p99 and
+// max of h multiplied by scale, with unit appended to the keys. Percentiles
+// are bucket upper bounds, so they are accurate to the runtime's bucket
+// resolution.
+func histogramSummary(h *rtmetrics.Float64Histogram, unit string, scale float64) gin.H {
+	var total uint64
+	for _, n := range h.Counts {
+		total += n
+	}
+	out := gin.H{"count": total}
+	if total == 0 {
+		return out
+	}
+	for _, p := range []struct {
+		key string
+		q   float64
+	}{{"p50", 0.50}, {"p90", 0.90}, {"p99", 0.99}, {"max", 1}} {
+		out[p.key+unit] = roundTo(histogramQuantile(h, total, p.q)*scale, 6)
+	}
+	return out
+}
+
+// histogramQuantile returns the upper bound of the bucket holding the q-th
+// observation (nearest rank), falling back to the lower bound for the
+// open-ended last bucket
+func histogramQuantile(h *rtmetrics.Float64Histogram, total uint64, q float64) float64 {
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, n := range h.Counts {
+		seen += n
+		if seen >= rank && n > 0 {
+			if upper := h.Buckets[i+1]; !math.IsInf(upper, 1) {
+				return upper
+			}
+			return h.Buckets[i]
+		}
+	}
+	return h.Buckets[len(h.Buckets)-1]
+}
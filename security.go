@@ -0,0 +1,60 @@
+package osinfo
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCSP allows the dashboard's inline script and style blocks as well as
+// the Tailwind and Chart.js CDNs it loads.
+const defaultCSP = "default-src 'self'; " +
+	"script-src 'self' 'unsafe-inline' https://cdn.tailwindcss.com https://cdn.jsdelivr.net; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"frame-ancestors 'none'"
+
+func defaultSecurityHeaders() map[string]string {
+	return map[string]string{
+		"Content-Security-Policy": defaultCSP,
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+	}
+}
+
+// securityHeadersMiddleware sets the configured security headers on the
+// dashboard and static asset responses. Under WithEmbeddable the
+// frame-ancestors directive is merged into the CSP and X-Frame-Options is
+// left out, whatever order the options were given in.
+func securityHeadersMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for k, v := range cfg().securityHeaders {
+			if cfg().frameAncestors != nil && (k == "Content-Security-Policy" || k == "X-Frame-Options") {
+				continue
+			}
+			c.Header(k, v)
+		}
+		if cfg().frameAncestors != nil {
+			c.Header("Content-Security-Policy",
+				withFrameAncestors(cfg().securityHeaders["Content-Security-Policy"], cfg().frameAncestors))
+		}
+		c.Next()
+	}
+}
+
+// withFrameAncestors replaces the frame-ancestors directive of csp, or adds
+// one if it is missing
+func withFrameAncestors(csp string, sources []string) string {
+	directive := "frame-ancestors " + strings.Join(sources, " ")
+	parts := strings.Split(csp, ";")
+	out := parts[:0]
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "frame-ancestors") {
+			continue
+		}
+		out = append(out, p)
+	}
+	return strings.Join(append(out, directive), "; ")
+}
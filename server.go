@@ -0,0 +1,48 @@
+package osinfo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListenAndServe serves the osinfo endpoints alone, at the root of a
+// dedicated engine, on addr, e.g. an internal ":9090" next to a public API
+// port. The listener is opened before returning, so address errors are
+// reported directly; the server then runs in the background until the
+// returned shutdown function is called. Shutdown only stops this server, not
+// the background goroutines, which the package-level Shutdown stops.
+//
+// The metrics middleware is only installed on the dedicated engine; add
+// Middleware to the application's engine so /metrics counts its requests.
+//
+// The package has a single configuration, so opts also apply to any other
+// RegisterRoutes mount in the process, and options given there apply here:
+// WithBasicAuth, WithEnvelope or WithFields change both servers. Only the
+// Config.Prefix of a WithConfig is limited to the call it is passed to.
+func ListenAndServe(addr string, opts ...Option) (*http.Server, func(context.Context) error, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	RegisterRoutes(engine, "/", opts...)
+
+	srv := &http.Server{
+		Handler:           engine,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("osinfo: server on %s stopped: %v", addr, err)
+		}
+	}()
+	return srv, srv.Shutdown, nil
+}
@@ -0,0 +1,38 @@
+package osinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListenAndServeCountsAppRequests(t *testing.T) {
+	resetGlobals(t)
+	app := gin.New()
+	app.Use(Middleware())
+	// /users is also an osinfo endpoint on the internal engine
+	app.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	srv, shutdown, err := ListenAndServe("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { shutdown(context.Background()) })
+
+	for i := 0; i < 5; i++ {
+		get(app, "/users")
+	}
+	w := get(srv.Handler, "/metrics")
+	var body struct {
+		TotalRequests int64 `json:"total_requests"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.TotalRequests != 5 {
+		t.Errorf("total_requests = %d after 5 app requests, want 5", body.TotalRequests)
+	}
+}
@@ -0,0 +1,107 @@
+package osinfo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSnapshots bounds how many snapshots are kept; the oldest is dropped
+// when a new one is taken
+const maxSnapshots = 32
+
+type snapshot struct {
+	id     string
+	taken  time.Time
+	values map[string]float64
+}
+
+var snapshots = struct {
+	mu    sync.Mutex
+	order []string
+	byID  map[string]snapshot
+}{byID: make(map[string]snapshot)}
+
+// snapshotValues reads the numbers /diff compares. Values whose collector
+// fails are left out.
+func snapshotValues() map[string]float64 {
+	v := map[string]float64{
+		"total_requests":      float64(metrics.TotalRequests.Load()),
+		"total_response_ms":   float64(metrics.TotalResponseTime.Load()),
+		"goroutines":          float64(runtime.NumGoroutine()),
+		"server_uptime_secs":  since(metrics.StartTime).Seconds(),
+		"status_5xx_requests": 0,
+	}
+	for code, n := range metrics.StatusCodes() {
+		if code >= 500 {
+			v["status_5xx_requests"] += float64(n)
+		}
+	}
+	if p, err := instantCPUPercent(&snapshotCPU); err == nil && len(p) > 0 {
+		v["cpu_percent"] = p[0]
+	}
+	if m, err := sys.VirtualMemory(); err == nil {
+		v["mem_used"] = float64(m.Used)
+		v["mem_used_percent"] = m.UsedPercent
+	}
+	if u, err := mountUsage(cfg().rootMount); err == nil {
+		v["disk_root_used"] = float64(u.Used)
+		v["disk_root_used_percent"] = u.UsedPercent
+	}
+	return v
+}
+
+// snapshotHandler stores the current values and returns an ID for /diff
+func snapshotHandler(c *gin.Context) {
+	b := make([]byte, 8)
+	rand.Read(b)
+	s := snapshot{id: hex.EncodeToString(b), taken: clk.Now(), values: snapshotValues()}
+
+	snapshots.mu.Lock()
+	if len(snapshots.order) >= maxSnapshots {
+		delete(snapshots.byID, snapshots.order[0])
+		snapshots.order = snapshots.order[1:]
+	}
+	snapshots.order = append(snapshots.order, s.id)
+	snapshots.byID[s.id] = s
+	snapshots.mu.Unlock()
+
+	respond(c, http.StatusCreated, gin.H{
+		"id":     s.id,
+		"time":   s.taken,
+		"values": s.values,
+	})
+}
+
+// diffHandler compares the snapshot ?from=ID with the current values
+func diffHandler(c *gin.Context) {
+	id := c.Query("from")
+	snapshots.mu.Lock()
+	from, ok := snapshots.byID[id]
+	snapshots.mu.Unlock()
+	if !ok {
+		respond(c, http.StatusNotFound, gin.H{"error": "unknown snapshot: " + id})
+		return
+	}
+
+	now := snapshotValues()
+	changes := gin.H{}
+	for name, before := range from.values {
+		after, ok := now[name]
+		if !ok {
+			continue
+		}
+		changes[name] = gin.H{"from": before, "to": after, "delta": after - before}
+	}
+	respond(c, http.StatusOK, gin.H{
+		"from":            from.id,
+		"from_time":       from.taken,
+		"elapsed_seconds": since(from.taken).Seconds(),
+		"changes":         changes,
+	})
+}
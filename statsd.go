@@ -0,0 +1,121 @@
+package osinfo
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"time"
+)
+
+// statsdInterval is how often WithStatsD flushes
+const statsdInterval = 10 * time.Second
+
+// statsdMaxPacket keeps each datagram under a typical Ethernet MTU
+const statsdMaxPacket = 1432
+
+// runStatsD flushes the request metrics and system gauges to a StatsD server
+// every statsdInterval. Request and status counts are sent as counters of
+// the requests seen since the previous flush; latency is the mean over the
+// same interval.
+func runStatsD(addr, prefix string, stop <-chan struct{}) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		log.Printf("osinfo: statsd: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	if prefix != "" && prefix[len(prefix)-1] != '.' {
+		prefix += "."
+	}
+	var last statsdTotals
+	ticker := time.NewTicker(statsdInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := readStatsdTotals()
+		lines := statsdLines(prefix, last, now)
+		last = now
+		for _, packet := range statsdPackets(lines) {
+			// write errors are usually just no listener yet; logging them
+			// every flush would only be noise
+			conn.Write(packet)
+		}
+	}
+}
+
+type statsdTotals struct {
+	requests     int64
+	responseTime int64
+	statusCodes  map[int]int64
+}
+
+func readStatsdTotals() statsdTotals {
+	return statsdTotals{
+		requests:     metrics.TotalRequests.Load(),
+		responseTime: metrics.TotalResponseTime.Load(),
+		statusCodes:  metrics.StatusCodes(),
+	}
+}
+
+func statsdLines(prefix string, last, now statsdTotals) []string {
+	var lines []string
+	requests := now.requests - last.requests
+	lines = append(lines, fmt.Sprintf("%srequests:%d|c", prefix, requests))
+	if requests > 0 {
+		avg := float64(now.responseTime-last.responseTime) / float64(requests)
+		lines = append(lines, fmt.Sprintf("%sresponse_time_ms:%g|ms", prefix, avg))
+	}
+
+	codes := make([]int, 0, len(now.statusCodes))
+	for code := range now.statusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		if n := now.statusCodes[code] - last.statusCodes[code]; n > 0 {
+			lines = append(lines, fmt.Sprintf("%sstatus.%d:%d|c", prefix, code, n))
+		}
+	}
+
+	gauges := systemGauges(&statsdCPU)
+	names := make([]string, 0, len(gauges))
+	for name := range gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v, ok := gauges[name].(float64); ok {
+			lines = append(lines, fmt.Sprintf("%ssystem.%s:%g|g", prefix, name, v))
+		}
+	}
+	return lines
+}
+
+// statsdPackets packs newline-separated lines into datagrams of at most
+// statsdMaxPacket bytes
+func statsdPackets(lines []string) [][]byte {
+	var packets [][]byte
+	var buf bytes.Buffer
+	for _, l := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(l) > statsdMaxPacket {
+			packets = append(packets, append([]byte(nil), buf.Bytes()...))
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(l)
+	}
+	if buf.Len() > 0 {
+		packets = append(packets, buf.Bytes())
+	}
+	return packets
+}
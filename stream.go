@@ -0,0 +1,131 @@
+package osinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHub fans one sampling goroutine out to every /dashboard-stream
+// client. The sampler starts with the first client and stops with the last,
+// so idle servers don't collect anything.
+var streamHub = struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	quit    chan struct{}
+}{clients: make(map[chan []byte]struct{})}
+
+// subscribe adds a client, or reports false when cfg().maxStreamClients are
+// already connected
+func subscribe() (chan []byte, bool) {
+	streamHub.mu.Lock()
+	defer streamHub.mu.Unlock()
+
+	if len(streamHub.clients) >= cfg().maxStreamClients {
+		return nil, false
+	}
+	ch := make(chan []byte, 1)
+	streamHub.clients[ch] = struct{}{}
+	if streamHub.quit == nil {
+		streamHub.quit = make(chan struct{})
+		go runStreamSampler(cfg().dashboardRefresh, streamHub.quit)
+	}
+	return ch, true
+}
+
+func unsubscribe(ch chan []byte) {
+	streamHub.mu.Lock()
+	defer streamHub.mu.Unlock()
+
+	delete(streamHub.clients, ch)
+	if len(streamHub.clients) == 0 && streamHub.quit != nil {
+		close(streamHub.quit)
+		streamHub.quit = nil
+	}
+}
+
+// runStreamSampler collects the dashboard data every interval and hands it to
+// each client. A client that hasn't taken the previous sample yet skips this
+// one rather than holding up the others.
+func runStreamSampler(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		broadcast(streamPayload())
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+	}
+}
+
+func streamPayload() []byte {
+	b, err := json.Marshal(cleanDashboardData())
+	if err != nil {
+		b, _ = json.Marshal(gin.H{"error": err.Error()})
+	}
+	return b
+}
+
+func broadcast(b []byte) {
+	streamHub.mu.Lock()
+	defer streamHub.mu.Unlock()
+
+	for ch := range streamHub.clients {
+		select {
+		case ch <- b:
+		default:
+		}
+	}
+}
+
+// dashboardStreamHandler sends the dashboard data as server-sent events at
+// the dashboard refresh interval. New clients get a 503 once
+// WithMaxStreamClients connections are open.
+func dashboardStreamHandler(c *gin.Context) {
+	if refuseStream(c) {
+		return
+	}
+	ch, ok := subscribe()
+	if !ok {
+		c.Header("Retry-After", "10")
+		respond(c, http.StatusServiceUnavailable, gin.H{"error": "too many streaming clients"})
+		return
+	}
+	defer unsubscribe(ch)
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case b := <-ch:
+			c.SSEvent("dashboard", string(b))
+			c.Writer.Flush()
+		case <-ctx.Done():
+			return
+		case <-streamsDone():
+			return
+		}
+	}
+}
+
+// refuseStream answers 503 once Shutdown has run, as a new stream would be
+// closed straight away
+func refuseStream(c *gin.Context) bool {
+	select {
+	case <-streamsDone():
+		respond(c, http.StatusServiceUnavailable, gin.H{"error": "shutting down"})
+		return true
+	default:
+		return false
+	}
+}
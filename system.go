@@ -0,0 +1,158 @@
+package osinfo
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	cpu "github.com/shirou/gopsutil/v3/cpu"
+	disk "github.com/shirou/gopsutil/v3/disk"
+	host "github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	mem "github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// systemCollector wraps the gopsutil calls used by the handlers so they can
+// be replaced, e.g. in tests
+type systemCollector interface {
+	HostInfo() (*host.InfoStat, error)
+	Uptime() (uint64, error)
+	BootTime() (uint64, error)
+	Virtualization() (string, string, error)
+	Users() ([]host.UserStat, error)
+	VirtualMemory() (*mem.VirtualMemoryStat, error)
+	CPUPercent(interval time.Duration, percpu bool) ([]float64, error)
+	CPUTimes(percpu bool) ([]cpu.TimesStat, error)
+	Partitions(all bool) ([]disk.PartitionStat, error)
+	DiskUsage(path string) (*disk.UsageStat, error)
+	DiskIOCounters() (map[string]disk.IOCountersStat, error)
+	NetIOCounters(pernic bool) ([]net.IOCountersStat, error)
+	Pids() ([]int32, error)
+	LoadAvg() (*load.AvgStat, error)
+}
+
+type gopsutilCollector struct{}
+
+func (gopsutilCollector) HostInfo() (*host.InfoStat, error) { return host.Info() }
+func (gopsutilCollector) Uptime() (uint64, error)           { return host.Uptime() }
+func (gopsutilCollector) BootTime() (uint64, error)         { return host.BootTime() }
+func (gopsutilCollector) Virtualization() (string, string, error) {
+	return host.Virtualization()
+}
+func (gopsutilCollector) Users() ([]host.UserStat, error) { return host.Users() }
+func (gopsutilCollector) VirtualMemory() (*mem.VirtualMemoryStat, error) {
+	return mem.VirtualMemory()
+}
+func (gopsutilCollector) CPUPercent(interval time.Duration, percpu bool) ([]float64, error) {
+	return cpu.Percent(interval, percpu)
+}
+func (gopsutilCollector) CPUTimes(percpu bool) ([]cpu.TimesStat, error) {
+	return cpu.Times(percpu)
+}
+func (gopsutilCollector) Partitions(all bool) ([]disk.PartitionStat, error) {
+	return disk.Partitions(all)
+}
+func (gopsutilCollector) DiskUsage(path string) (*disk.UsageStat, error) { return disk.Usage(path) }
+func (gopsutilCollector) DiskIOCounters() (map[string]disk.IOCountersStat, error) {
+	return disk.IOCounters()
+}
+func (gopsutilCollector) NetIOCounters(pernic bool) ([]net.IOCountersStat, error) {
+	return net.IOCounters(pernic)
+}
+
+func (gopsutilCollector) Pids() ([]int32, error) { return process.Pids() }
+
+func (gopsutilCollector) LoadAvg() (*load.AvgStat, error) { return load.Avg() }
+
+var sys systemCollector = gopsutilCollector{}
+
+// isPermissionError reports whether err looks like the collector was denied
+// access, as happens under restrictive seccomp profiles or in locked-down
+// containers. gopsutil does not always wrap the underlying error, so the
+// message is checked as well.
+func isPermissionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "permission denied") ||
+		strings.Contains(msg, "operation not permitted")
+}
+
+// collectorError writes the error response for a failed collector. A
+// collector with an open circuit breaker yields a 503, and permission errors
+// a degraded 200 so probes don't flap on restricted hosts. The breaker is
+// checked first because its error embeds the last failure's message.
+func collectorError(c *gin.Context, err error) {
+	var open *breakerOpenError
+	if errors.As(err, &open) {
+		c.Header("Retry-After", strconv.Itoa(int(open.until.Sub(clk.Now()).Seconds())+1))
+		respond(c, http.StatusServiceUnavailable, gin.H{
+			"error":       err.Error(),
+			"unavailable": true,
+		})
+		return
+	}
+	if isPermissionError(err) {
+		respond(c, http.StatusOK, gin.H{
+			"degraded": true,
+			"note":     "collector is not permitted to read this data on this host",
+			"error":    err.Error(),
+		})
+		return
+	}
+	respond(c, http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// SystemGauges samples the headline host gauges: cpu_percent,
+// mem_used_percent and disk_root_used_percent. Gauges whose collector fails
+// are left out.
+func SystemGauges() map[string]float64 {
+	out := map[string]float64{}
+	for k, v := range systemGauges(&exportCPU) {
+		if f, ok := v.(float64); ok {
+			out[k] = f
+		}
+	}
+	return out
+}
+
+// systemGauges samples a few headline host gauges, with CPU read against
+// base. Each collector failure is reported under "errors" rather than failing
+// the whole result.
+func systemGauges(base *cpuBaseline) gin.H {
+	out := gin.H{}
+	errs := gin.H{}
+
+	if p, err := instantCPUPercent(base); err != nil {
+		errs["cpu"] = err.Error()
+	} else if len(p) > 0 {
+		out["cpu_percent"] = p[0]
+	}
+
+	if m, err := sys.VirtualMemory(); err != nil {
+		errs["mem"] = err.Error()
+	} else {
+		out["mem_used_percent"] = m.UsedPercent
+	}
+
+	if u, err := mountUsage(cfg().rootMount); err != nil {
+		errs["disk"] = err.Error()
+	} else {
+		out["disk_root_used_percent"] = u.UsedPercent
+	}
+
+	if len(errs) > 0 {
+		out["errors"] = errs
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package osinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// restrictedCollector behaves like a host whose seccomp profile denies the
+// memory, partition and load reads
+type restrictedCollector struct{ gopsutilCollector }
+
+func (restrictedCollector) VirtualMemory() (*mem.VirtualMemoryStat, error) {
+	return nil, os.ErrPermission
+}
+
+func (restrictedCollector) Partitions(bool) ([]disk.PartitionStat, error) {
+	return nil, fmt.Errorf("open /proc/self/mountinfo: %w", os.ErrPermission)
+}
+
+func (restrictedCollector) LoadAvg() (*load.AvgStat, error) {
+	return nil, fmt.Errorf("operation not permitted")
+}
+
+func TestPermissionErrorsAreDegraded(t *testing.T) {
+	r := newTestEngine(t, "/os")
+	sys = restrictedCollector{}
+
+	for _, target := range []string{"/os/mem", "/os/disk", "/os/load"} {
+		w := get(r, target)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", target, w.Code)
+			continue
+		}
+		var body struct {
+			Degraded bool   `json:"degraded"`
+			Note     string `json:"note"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("GET %s: %v", target, err)
+		}
+		if !body.Degraded || body.Note == "" {
+			t.Errorf("GET %s = %s, want degraded with a note", target, w.Body)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package osinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Thresholds are usage percentages above which the host is considered
+// unhealthy. A zero value disables that check.
+type Thresholds struct {
+	CPUPercent  float64
+	MemPercent  float64
+	DiskPercent float64
+}
+
+// thresholdReading is the current value of one thresholded metric
+type thresholdReading struct {
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Breached  bool    `json:"breached"`
+}
+
+// evaluateThresholds samples every metric that has a threshold configured.
+// CPU is read against base. Metrics that cannot be read are skipped.
+func evaluateThresholds(t Thresholds, base *cpuBaseline) []thresholdReading {
+	var out []thresholdReading
+	add := func(metric string, value, threshold float64) {
+		out = append(out, thresholdReading{
+			Metric:    metric,
+			Value:     value,
+			Threshold: threshold,
+			Breached:  value >= threshold,
+		})
+	}
+
+	if t.CPUPercent > 0 {
+		if p, err := instantCPUPercent(base); err == nil && len(p) > 0 {
+			add("cpu", p[0], t.CPUPercent)
+		}
+	}
+	if t.MemPercent > 0 {
+		if m, err := sys.VirtualMemory(); err == nil {
+			add("mem", m.UsedPercent, t.MemPercent)
+		}
+	}
+	if t.DiskPercent > 0 {
+		if u, err := mountUsage(cfg().rootMount); err == nil {
+			add("disk", u.UsedPercent, t.DiskPercent)
+		}
+	}
+	return out
+}
+
+// alertPayload is the JSON body POSTed to the alert webhook
+type alertPayload struct {
+	Hostname string    `json:"hostname"`
+	State    string    `json:"state"`
+	Time     time.Time `json:"time"`
+	thresholdReading
+}
+
+// runAlerter evaluates the thresholds every interval and posts to the
+// webhook when a metric starts or stops breaching its threshold. Only state
+// changes are sent, so a metric that stays high doesn't spam the webhook. A
+// change counts once cfg().alertDebounce consecutive checks agree, and is
+// only recorded once the webhook accepts it, so a failed post is retried.
+func runAlerter(url string, interval time.Duration, stop <-chan struct{}) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	firing := map[string]bool{}
+	// streak counts the consecutive checks that disagree with firing
+	streak := map[string]int{}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		for _, r := range evaluateThresholds(cfg().thresholds, &alertCPU) {
+			if r.Breached == firing[r.Metric] {
+				streak[r.Metric] = 0
+				continue
+			}
+			if streak[r.Metric]++; streak[r.Metric] < cfg().alertDebounce {
+				continue
+			}
+			state := "resolved"
+			if r.Breached {
+				state = "firing"
+			}
+			if sendAlert(client, url, alertPayload{
+				Hostname:         hostname(),
+				State:            state,
+				Time:             clk.Now(),
+				thresholdReading: r,
+			}) {
+				firing[r.Metric] = r.Breached
+				streak[r.Metric] = 0
+			}
+		}
+	}
+}
+
+// sendAlert posts alert and reports whether the webhook answered 2xx
+func sendAlert(client *http.Client, url string, alert alertPayload) bool {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("osinfo: sending alert: %v", err)
+		return false
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("osinfo: alert webhook returned %s", resp.Status)
+		return false
+	}
+	return true
+}
+
+func hostname() string {
+	h, err := sys.HostInfo()
+	if err != nil || h == nil {
+		return ""
+	}
+	return h.Hostname
+}
@@ -0,0 +1,36 @@
+package osinfo
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usersHandler lists the login sessions on the host. Headless servers and
+// containers usually have none, or no utmp file at all, which is reported as
+// an empty list.
+func usersHandler(c *gin.Context) {
+	writeCollected(c, "users", collectUsers)
+}
+
+func collectUsers() (any, error) {
+	users, err := sys.Users()
+	if errors.Is(err, os.ErrNotExist) {
+		users, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]gin.H, 0, len(users))
+	for _, u := range users {
+		sessions = append(sessions, gin.H{
+			"username":   u.User,
+			"terminal":   u.Terminal,
+			"host":       u.Host,
+			"login_time": time.Unix(int64(u.Started), 0).UTC(),
+		})
+	}
+	return sessions, nil
+}
@@ -0,0 +1,64 @@
+package osinfo
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build metadata reported by /version. Set them at link time, e.g.
+//
+//	go build -ldflags "-X github.com/raza001/go-osinfo-gin.Version=1.4.0 \
+//	  -X github.com/raza001/go-osinfo-gin.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/raza001/go-osinfo-gin.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// versionHandler reports the ldflags build metadata, when set, together with
+// the module, VCS details, cgo status and build settings embedded by the Go
+// toolchain. gopsutil behaves differently on some platforms with and without
+// cgo, so cgo_enabled helps explain platform discrepancies.
+func versionHandler(c *gin.Context) {
+	out := gin.H{
+		"go_version": runtime.Version(),
+		"compiler":   runtime.Compiler,
+	}
+	if Version != "" {
+		out["version"] = Version
+	}
+	if Commit != "" {
+		out["commit"] = Commit
+	}
+	if BuildDate != "" {
+		out["build_date"] = BuildDate
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		out["module"] = info.Main.Path
+		out["module_version"] = info.Main.Version
+		vcs := gin.H{}
+		build := gin.H{}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs", "vcs.revision", "vcs.time", "vcs.modified":
+				vcs[s.Key] = s.Value
+			case "CGO_ENABLED":
+				out["cgo_enabled"] = s.Value == "1"
+			case "-tags", "GOOS", "GOARCH", "-trimpath", "-race":
+				build[s.Key] = s.Value
+			}
+		}
+		if len(build) > 0 {
+			out["build_settings"] = build
+		}
+		if len(vcs) > 0 {
+			out["vcs"] = vcs
+		}
+	}
+	respond(c, http.StatusOK, out)
+}